@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// attributeLimiter caps the number of attributes per measurement. The zero
+// value, or a max of 0, leaves measurements alone.
+type attributeLimiter struct {
+	max int
+	// warned holds the keys already logged as dropped, so a limit that is
+	// hit on every iteration is only reported once per key.
+	warned map[attribute.Key]bool
+}
+
+func newAttributeLimiter(max int) *attributeLimiter {
+	return &attributeLimiter{max: max, warned: make(map[attribute.Key]bool)}
+}
+
+// apply returns at most max of kvs. Beyond the limit, the attributes are
+// sorted by key and deduplicated like an attribute set, and the ones after
+// the first max are dropped, so the same keys survive whatever order they
+// were added in.
+func (l *attributeLimiter) apply(kvs []attribute.KeyValue) []attribute.KeyValue {
+	if l == nil || l.max <= 0 || len(kvs) <= l.max {
+		return kvs
+	}
+	set := attribute.NewSet(kvs...)
+	sorted := set.ToSlice()
+	if len(sorted) <= l.max {
+		return sorted
+	}
+	for _, kv := range sorted[l.max:] {
+		if !l.warned[kv.Key] {
+			l.warned[kv.Key] = true
+			log.Printf("Dropping attribute %q: more than %d attributes per measurement", kv.Key, l.max)
+		}
+	}
+	return sorted[:l.max]
+}
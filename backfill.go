@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// runBackfill exports hand-built requests.total and cpu.usage data points
+// timestamped from clk's now back to now-span, one export per step. The SDK always
+// stamps measurements with the current time, so these bypass it and go to
+// the exporter directly. Points are sent newest first, so the backend sees
+// them out of order. It returns the number of exports sent.
+func runBackfill(ctx context.Context, exporter sdkmetric.Exporter, res *resource.Resource, span, step time.Duration, clk clock, r *rand.Rand) (int, error) {
+	now := clk.Now()
+	start := now.Add(-span)
+	steps := int(span / step)
+
+	// The counter is cumulative from start, so its values have to be worked
+	// out oldest first even though they are exported in reverse.
+	totals := make([]int64, steps+1)
+	var total int64
+	for i := range totals {
+		total += int64(r.Intn(10))
+		totals[i] = total
+	}
+
+	scope := instrumentation.Scope{Name: "otel-demo"}
+	host := attribute.NewSet(attribute.String("host", "demo-host"))
+	for i := steps; i >= 0; i-- {
+		ts := start.Add(time.Duration(i) * step)
+		rm := &metricdata.ResourceMetrics{
+			Resource: res,
+			ScopeMetrics: []metricdata.ScopeMetrics{{
+				Scope: scope,
+				Metrics: []metricdata.Metrics{
+					{
+						Name:        "requests.total",
+						Description: "Total number of requests",
+						Data: metricdata.Sum[int64]{
+							DataPoints: []metricdata.DataPoint[int64]{{
+								StartTime: start,
+								Time:      ts,
+								Value:     totals[i],
+							}},
+							Temporality: metricdata.CumulativeTemporality,
+							IsMonotonic: true,
+						},
+					},
+					{
+						Name:        "cpu.usage",
+						Description: "Current CPU usage percentage",
+						Data: metricdata.Gauge[float64]{
+							DataPoints: []metricdata.DataPoint[float64]{{
+								Attributes: host,
+								Time:       ts,
+								Value:      r.Float64() * 100,
+							}},
+						},
+					},
+				},
+			}},
+		}
+		if err := exporter.Export(ctx, rm); err != nil {
+			return steps - i, fmt.Errorf("failed to export backfill point at %s: %w", ts.Format(time.RFC3339), err)
+		}
+	}
+	return steps + 1, nil
+}
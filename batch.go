@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// batchingExporter splits every export into requests of at most limit data
+// points, for collectors that reject large messages with ResourceExhausted.
+type batchingExporter struct {
+	sdkmetric.Exporter
+	limit int
+}
+
+func (e *batchingExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	var errs []error
+	for _, chunk := range splitResourceMetrics(rm, e.limit) {
+		if err := e.Exporter.Export(ctx, chunk); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitResourceMetrics partitions rm into chunks holding at most limit data
+// points each, keeping the order of scopes and metrics. A metric whose points
+// don't fit in the current chunk is continued in the next one.
+func splitResourceMetrics(rm *metricdata.ResourceMetrics, limit int) []*metricdata.ResourceMetrics {
+	if limit <= 0 {
+		return []*metricdata.ResourceMetrics{rm}
+	}
+
+	var chunks []*metricdata.ResourceMetrics
+	cur := &metricdata.ResourceMetrics{Resource: rm.Resource}
+	points := 0
+	for _, sm := range rm.ScopeMetrics {
+		scopeOpen := false
+		for _, m := range sm.Metrics {
+			total := dataPointCount(m.Data)
+			for start := 0; start < total; {
+				if points == limit {
+					chunks = append(chunks, cur)
+					cur = &metricdata.ResourceMetrics{Resource: rm.Resource}
+					points = 0
+					scopeOpen = false
+				}
+				if !scopeOpen {
+					cur.ScopeMetrics = append(cur.ScopeMetrics, metricdata.ScopeMetrics{Scope: sm.Scope})
+					scopeOpen = true
+				}
+				end := min(total, start+limit-points)
+				part := m
+				part.Data = sliceDataPoints(m.Data, start, end)
+				last := &cur.ScopeMetrics[len(cur.ScopeMetrics)-1]
+				last.Metrics = append(last.Metrics, part)
+				points += end - start
+				start = end
+			}
+		}
+	}
+	if points > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
+
+// dataPointCount returns the number of data points in data. Aggregations it
+// doesn't know are counted as a single point and never split.
+func dataPointCount(data metricdata.Aggregation) int {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64]:
+		return len(d.DataPoints)
+	case metricdata.Gauge[float64]:
+		return len(d.DataPoints)
+	case metricdata.Sum[int64]:
+		return len(d.DataPoints)
+	case metricdata.Sum[float64]:
+		return len(d.DataPoints)
+	case metricdata.Histogram[int64]:
+		return len(d.DataPoints)
+	case metricdata.Histogram[float64]:
+		return len(d.DataPoints)
+	case metricdata.ExponentialHistogram[int64]:
+		return len(d.DataPoints)
+	case metricdata.ExponentialHistogram[float64]:
+		return len(d.DataPoints)
+	case metricdata.Summary:
+		return len(d.DataPoints)
+	default:
+		return 1
+	}
+}
+
+// sliceDataPoints returns a copy of data holding only the points in
+// [start, end).
+func sliceDataPoints(data metricdata.Aggregation, start, end int) metricdata.Aggregation {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.Gauge[float64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.Sum[int64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.Sum[float64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.Histogram[int64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.Histogram[float64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.ExponentialHistogram[int64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.ExponentialHistogram[float64]:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	case metricdata.Summary:
+		d.DataPoints = d.DataPoints[start:end]
+		return d
+	default:
+		return data
+	}
+}
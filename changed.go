@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// seriesKey identifies a series across exports.
+type seriesKey struct {
+	metric string
+	attrs  attribute.Distinct
+}
+
+// seriesValue is what a cumulative point is compared by: the value of a sum,
+// or the count and sum of a histogram.
+type seriesValue struct {
+	value float64
+	count uint64
+}
+
+// changedOnlyExporter drops cumulative sum and histogram points whose value
+// is the same as in the last successful export, so idle series cost nothing
+// on the wire. Gauges and delta points are always exported. This is lossy: a
+// backend sees gaps for idle series and may treat them as stale.
+type changedOnlyExporter struct {
+	sdkmetric.Exporter
+
+	mu   sync.Mutex
+	last map[seriesKey]seriesValue
+}
+
+func (e *changedOnlyExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.last == nil {
+		e.last = make(map[seriesKey]seriesValue)
+	}
+
+	// As with promotingExporter, the filtered copy is built next to rm
+	// because the reader reuses its slices.
+	seen := make(map[seriesKey]seriesValue)
+	out := &metricdata.ResourceMetrics{Resource: rm.Resource}
+	for _, sm := range rm.ScopeMetrics {
+		scope := metricdata.ScopeMetrics{Scope: sm.Scope}
+		for _, m := range sm.Metrics {
+			data, ok := e.changed(m.Name, m.Data, seen)
+			if !ok {
+				continue
+			}
+			m.Data = data
+			scope.Metrics = append(scope.Metrics, m)
+		}
+		if len(scope.Metrics) > 0 {
+			out.ScopeMetrics = append(out.ScopeMetrics, scope)
+		}
+	}
+
+	// Only remember what was delivered, so a failed export is sent again.
+	if err := e.Exporter.Export(ctx, out); err != nil {
+		return err
+	}
+	for key, v := range seen {
+		e.last[key] = v
+	}
+	return nil
+}
+
+// changed returns data with the unchanged cumulative points removed, adding
+// the values of the kept ones to seen. It reports false if no point is left.
+func (e *changedOnlyExporter) changed(name string, data metricdata.Aggregation, seen map[seriesKey]seriesValue) (metricdata.Aggregation, bool) {
+	switch d := data.(type) {
+	case metricdata.Sum[int64]:
+		if d.Temporality == metricdata.CumulativeTemporality {
+			d.DataPoints = changedPoints(e.last, seen, name, d.DataPoints)
+		}
+		return d, len(d.DataPoints) > 0
+	case metricdata.Sum[float64]:
+		if d.Temporality == metricdata.CumulativeTemporality {
+			d.DataPoints = changedPoints(e.last, seen, name, d.DataPoints)
+		}
+		return d, len(d.DataPoints) > 0
+	case metricdata.Histogram[int64]:
+		if d.Temporality == metricdata.CumulativeTemporality {
+			d.DataPoints = changedHistogramPoints(e.last, seen, name, d.DataPoints)
+		}
+		return d, len(d.DataPoints) > 0
+	case metricdata.Histogram[float64]:
+		if d.Temporality == metricdata.CumulativeTemporality {
+			d.DataPoints = changedHistogramPoints(e.last, seen, name, d.DataPoints)
+		}
+		return d, len(d.DataPoints) > 0
+	default:
+		return data, dataPointCount(data) > 0
+	}
+}
+
+func changedPoints[N int64 | float64](last, seen map[seriesKey]seriesValue, name string, pts []metricdata.DataPoint[N]) []metricdata.DataPoint[N] {
+	var out []metricdata.DataPoint[N]
+	for _, p := range pts {
+		key := seriesKey{metric: name, attrs: p.Attributes.Equivalent()}
+		v := seriesValue{value: float64(p.Value)}
+		if prev, ok := last[key]; ok && prev == v {
+			continue
+		}
+		seen[key] = v
+		out = append(out, p)
+	}
+	return out
+}
+
+func changedHistogramPoints[N int64 | float64](last, seen map[seriesKey]seriesValue, name string, pts []metricdata.HistogramDataPoint[N]) []metricdata.HistogramDataPoint[N] {
+	var out []metricdata.HistogramDataPoint[N]
+	for _, p := range pts {
+		key := seriesKey{metric: name, attrs: p.Attributes.Equivalent()}
+		v := seriesValue{value: float64(p.Sum), count: p.Count}
+		if prev, ok := last[key]; ok && prev == v {
+			continue
+		}
+		seen[key] = v
+		out = append(out, p)
+	}
+	return out
+}
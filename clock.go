@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// clock abstracts the wall clock so time-dependent code (the generation loop,
+// waveforms, uptime) can be driven deterministically. After is used instead of
+// a blocking Sleep so waits can still be interrupted by a context.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the clock used by default.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// scaledClock speeds waits up by scale (2 halves them). Now is left alone,
+// so only the pacing changes.
+type scaledClock struct {
+	clock
+	scale float64
+}
+
+func (c scaledClock) After(d time.Duration) <-chan time.Time {
+	return c.clock.After(time.Duration(float64(d) / c.scale))
+}
+
+// fakeClock only moves when waited on: After advances it by d and fires at
+// once. A loop driven by it runs as fast as it can while seeing the same
+// elapsed times as in real time, so its output depends only on the seed.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- now
+	return ch
+}
+
+// monotonicClock reports base plus the time elapsed since base as measured
+// by the wrapped clock. For the real clock with a base taken from time.Now,
+// that elapsed time comes from the monotonic clock, so stepping the wall
+// clock back (NTP corrections, a resumed VM) doesn't move timestamps back.
+// Successive calls always return strictly increasing times, even if the
+// wrapped clock itself goes backwards.
+type monotonicClock struct {
+	clock
+	base time.Time
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newMonotonicClock(c clock, base time.Time) *monotonicClock {
+	return &monotonicClock{clock: c, base: base}
+}
+
+func (c *monotonicClock) Now() time.Time {
+	// Round(0) drops the monotonic reading so the result is plain wall time.
+	now := c.base.Add(c.clock.Now().Sub(c.base)).Round(0)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !now.After(c.last) {
+		now = c.last.Add(time.Nanosecond)
+	}
+	c.last = now
+	return now
+}
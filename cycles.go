@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// exportCycles closes done once every push exporter has delivered n
+// exports successfully.
+type exportCycles struct {
+	n         int64
+	remaining atomic.Int64
+	done      chan struct{}
+}
+
+func newExportCycles(n int) *exportCycles {
+	return &exportCycles{n: int64(n), done: make(chan struct{})}
+}
+
+// wrap returns exporter counting its successful exports towards c. Exports
+// made while fallbackActive reports true never reached the endpoint, so they
+// don't count.
+func (c *exportCycles) wrap(exporter sdkmetric.Exporter, fallbackActive func() bool) sdkmetric.Exporter {
+	c.remaining.Add(1)
+	return &cycleCountingExporter{Exporter: exporter, cycles: c, fallbackActive: fallbackActive}
+}
+
+type cycleCountingExporter struct {
+	sdkmetric.Exporter
+	cycles         *exportCycles
+	fallbackActive func() bool
+	count          atomic.Int64
+}
+
+func (e *cycleCountingExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	err := e.Exporter.Export(ctx, rm)
+	if err == nil && !e.fallbackActive() && e.count.Add(1) == e.cycles.n {
+		if e.cycles.remaining.Add(-1) == 0 {
+			close(e.cycles.done)
+		}
+	}
+	return err
+}
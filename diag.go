@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// diagAttributeOrder records a counter twice with the same attributes in
+// opposite orders on a private provider and reports, from a manual collect,
+// whether the SDK folded them into one series. It returns an error if not.
+func diagAttributeOrder(ctx context.Context, w io.Writer) error {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer mp.Shutdown(ctx)
+
+	counter, err := mp.Meter("otel-demo/diag").Int64Counter("diag.attr_order")
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	method := attribute.String("method", "GET")
+	status := attribute.String("status", "200")
+	counter.Add(ctx, 1, metric.WithAttributes(method, status))
+	counter.Add(ctx, 1, metric.WithAttributes(status, method))
+	fmt.Fprintln(w, "Recorded diag.attr_order twice: {method, status} and {status, method}")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		return fmt.Errorf("failed to collect: %w", err)
+	}
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	if !ok {
+		return fmt.Errorf("unexpected aggregation %T", rm.ScopeMetrics[0].Metrics[0].Data)
+	}
+	for _, dp := range sum.DataPoints {
+		fmt.Fprintf(w, "  series %s = %d\n", dp.Attributes.Encoded(attribute.DefaultEncoder()), dp.Value)
+	}
+	if len(sum.DataPoints) != 1 {
+		return fmt.Errorf("got %d series, want 1", len(sum.DataPoints))
+	}
+	fmt.Fprintln(w, "Both orders collapsed into one series: attribute sets are sorted by key, so order does not affect identity")
+	return nil
+}
+
+// cardinalityStress records 10×limit distinct attribute sets into a counter
+// on a private provider limited to limit series per instrument, then checks
+// through a manual collect that the excess went into the overflow series
+// (otel.metric.overflow=true) and that no increment was lost.
+func cardinalityStress(ctx context.Context, w io.Writer, limit int) error {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithCardinalityLimit(limit),
+	)
+	defer mp.Shutdown(ctx)
+
+	counter, err := mp.Meter("otel-demo/diag").Int64Counter("diag.cardinality")
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	sets := 10 * limit
+	for i := range sets {
+		counter.Add(ctx, 1, metric.WithAttributes(attribute.Int("user.id", i)))
+	}
+	fmt.Fprintf(w, "Recorded %d distinct attribute sets with a cardinality limit of %d\n", sets, limit)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		return fmt.Errorf("failed to collect: %w", err)
+	}
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	if !ok {
+		return fmt.Errorf("unexpected aggregation %T", rm.ScopeMetrics[0].Metrics[0].Data)
+	}
+	overflowKey := attribute.Key("otel.metric.overflow")
+	var total, overflow int64
+	var series int
+	for _, dp := range sum.DataPoints {
+		total += dp.Value
+		if v, ok := dp.Attributes.Value(overflowKey); ok && v.AsBool() {
+			overflow = dp.Value
+			continue
+		}
+		series++
+	}
+	fmt.Fprintf(w, "  %d regular series, overflow series holds %d, total %d\n", series, overflow, total)
+
+	if overflow == 0 {
+		return fmt.Errorf("no %s=true data point", overflowKey)
+	}
+	if total != int64(sets) {
+		return fmt.Errorf("total is %d, want %d", total, sets)
+	}
+	fmt.Fprintln(w, "Overflow works: excess attribute sets were aggregated into the overflow series without losing any count")
+	return nil
+}
+
+// cardinalityGrowth adds 1000 new attribute sets to a counter on a private
+// provider every 100ms until ctx is done, and every 2 seconds reports how
+// many sets were recorded, how many series a manual collect returns and how
+// much heap is in use. With a positive limit the provider caps the series per
+// instrument, so the heap levels off once the overflow series takes over.
+func cardinalityGrowth(ctx context.Context, w io.Writer, limit int) error {
+	reader := sdkmetric.NewManualReader()
+	opts := []sdkmetric.Option{sdkmetric.WithReader(reader)}
+	if limit > 0 {
+		opts = append(opts, sdkmetric.WithCardinalityLimit(limit))
+	}
+	mp := sdkmetric.NewMeterProvider(opts...)
+	defer mp.Shutdown(context.Background())
+
+	counter, err := mp.Meter("otel-demo/diag").Int64Counter("diag.cardinality_growth")
+	if err != nil {
+		return fmt.Errorf("failed to create counter: %w", err)
+	}
+	if limit > 0 {
+		fmt.Fprintf(w, "Growing cardinality with a limit of %d series, press Ctrl+C to stop\n", limit)
+	} else {
+		fmt.Fprintln(w, "Growing cardinality without a limit, press Ctrl+C to stop")
+	}
+
+	grow := time.NewTicker(100 * time.Millisecond)
+	defer grow.Stop()
+	report := time.NewTicker(2 * time.Second)
+	defer report.Stop()
+	var sets int
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-grow.C:
+			for range 1000 {
+				counter.Add(ctx, 1, metric.WithAttributes(attribute.Int("user.id", sets)))
+				sets++
+			}
+		case <-report.C:
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(ctx, &rm); err != nil {
+				return fmt.Errorf("failed to collect: %w", err)
+			}
+			var series int
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					series += dataPointCount(m.Data)
+				}
+			}
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			fmt.Fprintf(w, "%d attribute sets recorded, %d series held, heap in use %.1f MiB\n",
+				sets, series, float64(ms.HeapInuse)/(1<<20))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync/atomic"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+// embeddedCollector is a minimal in-process OTLP/gRPC metrics receiver for
+// -embedded-collector. It only counts and logs what it receives.
+type embeddedCollector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	listener net.Listener
+	server   *grpc.Server
+	requests atomic.Int64
+	points   atomic.Int64
+}
+
+// startEmbeddedCollector listens on a free loopback port and serves the OTLP
+// metrics service on it in the background.
+func startEmbeddedCollector() (*embeddedCollector, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the embedded collector: %w", err)
+	}
+	c := &embeddedCollector{listener: listener, server: grpc.NewServer()}
+	colmetricpb.RegisterMetricsServiceServer(c.server, c)
+	go func() {
+		if err := c.server.Serve(listener); err != nil {
+			log.Printf("Embedded collector stopped: %v", err)
+		}
+	}()
+	return c, nil
+}
+
+// addr returns the host:port the collector listens on.
+func (c *embeddedCollector) addr() string {
+	return c.listener.Addr().String()
+}
+
+func (c *embeddedCollector) Export(_ context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	var metrics, points int
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				metrics++
+				points += protoDataPoints(m)
+			}
+		}
+	}
+	c.requests.Add(1)
+	c.points.Add(int64(points))
+	fmt.Printf("Embedded collector received %d metrics with %d data points\n", metrics, points)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+// protoDataPoints returns the number of data points of an OTLP metric.
+func protoDataPoints(m *metricpb.Metric) int {
+	switch data := m.GetData().(type) {
+	case *metricpb.Metric_Gauge:
+		return len(data.Gauge.GetDataPoints())
+	case *metricpb.Metric_Sum:
+		return len(data.Sum.GetDataPoints())
+	case *metricpb.Metric_Histogram:
+		return len(data.Histogram.GetDataPoints())
+	case *metricpb.Metric_ExponentialHistogram:
+		return len(data.ExponentialHistogram.GetDataPoints())
+	case *metricpb.Metric_Summary:
+		return len(data.Summary.GetDataPoints())
+	}
+	return 0
+}
+
+// stop waits for in-flight exports to finish, shuts the server down and
+// prints what it received. Call it after the meter provider has flushed.
+func (c *embeddedCollector) stop() {
+	c.server.GracefulStop()
+	fmt.Printf("Embedded collector stopped after %d export requests with %d data points\n", c.requests.Load(), c.points.Load())
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// emfUnits maps the UCUM units the demo uses to CloudWatch units; anything
+// else is reported as None.
+var emfUnits = map[string]string{
+	"ms": "Milliseconds",
+	"s":  "Seconds",
+	"By": "Bytes",
+	"1":  "Count",
+}
+
+// emfExporter writes every data point as one line of CloudWatch Embedded
+// Metric Format JSON, which CloudWatch Logs turns into metrics without a
+// collector. The data point's attributes become its dimensions and the
+// service name its namespace.
+type emfExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// newEMFExporter writes to stdout, or appends to the file at path if given.
+// Appending lets a second exporter (-runtime-metrics-interval) share the file.
+func newEMFExporter(path string) (*emfExporter, error) {
+	if path == "" {
+		return &emfExporter{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EMF file: %w", err)
+	}
+	return &emfExporter{w: f, closer: f}, nil
+}
+
+// Temporality is delta for the monotonic kinds: CloudWatch sums what each
+// record reports, so cumulative values would count everything again.
+func (e *emfExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	switch k {
+	case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram, sdkmetric.InstrumentKindObservableCounter:
+		return metricdata.DeltaTemporality
+	}
+	return metricdata.CumulativeTemporality
+}
+
+func (e *emfExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *emfExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	namespace := "otel-demo"
+	if v, ok := rm.Resource.Set().Value(semconv.ServiceNameKey); ok && v.AsString() != "" {
+		namespace = v.AsString()
+	}
+
+	var lines []byte
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			for _, record := range emfRecords(namespace, m) {
+				line, err := json.Marshal(record)
+				if err != nil {
+					return fmt.Errorf("failed to encode EMF record for %s: %w", m.Name, err)
+				}
+				lines = append(append(lines, line...), '\n')
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	// One write per export keeps lines from interleaving with other writers.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(lines); err != nil {
+		return fmt.Errorf("failed to write EMF records: %w", err)
+	}
+	return nil
+}
+
+func (e *emfExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *emfExporter) Shutdown(context.Context) error {
+	if e.closer != nil {
+		return e.closer.Close()
+	}
+	return nil
+}
+
+// emfValue is one CloudWatch metric within a record.
+type emfValue struct {
+	name  string
+	unit  string
+	value float64
+}
+
+// emfRecords returns an EMF record per data point of m. Histograms are
+// reported as their count, sum, min and max, each a metric of its own.
+func emfRecords(namespace string, m metricdata.Metrics) []map[string]any {
+	unit, ok := emfUnits[m.Unit]
+	if !ok {
+		unit = "None"
+	}
+
+	var records []map[string]any
+	add := func(attrs attribute.Set, t time.Time, values ...emfValue) {
+		records = append(records, emfRecord(namespace, attrs, t, values))
+	}
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, emfValue{m.Name, unit, float64(dp.Value)})
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, emfValue{m.Name, unit, dp.Value})
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, emfValue{m.Name, unit, float64(dp.Value)})
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, emfValue{m.Name, unit, dp.Value})
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, emfHistogramValues(m.Name, unit, dp.Count, float64(dp.Sum), dp.Min, dp.Max)...)
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, emfHistogramValues(m.Name, unit, dp.Count, dp.Sum, dp.Min, dp.Max)...)
+		}
+	}
+	return records
+}
+
+func emfHistogramValues[N int64 | float64](name, unit string, count uint64, sum float64, minimum, maximum metricdata.Extrema[N]) []emfValue {
+	values := []emfValue{
+		{name + ".count", "Count", float64(count)},
+		{name + ".sum", unit, sum},
+	}
+	if v, ok := minimum.Value(); ok {
+		values = append(values, emfValue{name + ".min", unit, float64(v)})
+	}
+	if v, ok := maximum.Value(); ok {
+		values = append(values, emfValue{name + ".max", unit, float64(v)})
+	}
+	return values
+}
+
+// emfRecord builds a record with the _aws metadata block, one member per
+// attribute (all of them dimensions) and one per value.
+func emfRecord(namespace string, attrs attribute.Set, t time.Time, values []emfValue) map[string]any {
+	record := make(map[string]any, attrs.Len()+len(values)+1)
+	dimensions := make([]string, 0, attrs.Len())
+	for _, kv := range attrs.ToSlice() {
+		dimensions = append(dimensions, string(kv.Key))
+		// CloudWatch dimension values are strings.
+		record[string(kv.Key)] = kv.Value.Emit()
+	}
+	metrics := make([]map[string]string, 0, len(values))
+	for _, v := range values {
+		metrics = append(metrics, map[string]string{"Name": v.name, "Unit": v.unit})
+		record[v.name] = v.value
+	}
+	record["_aws"] = map[string]any{
+		"Timestamp": t.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  namespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    metrics,
+		}},
+	}
+	return record
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Setup failures come in three kinds, so callers can tell them apart with
+// errors.As without matching on messages. Each one wraps its cause.
+
+// exporterError is a failure to create the named exporter.
+type exporterError struct {
+	exporter string
+	err      error
+}
+
+func (e *exporterError) Error() string {
+	return fmt.Sprintf("%s exporter: %v", e.exporter, e.err)
+}
+
+func (e *exporterError) Unwrap() error { return e.err }
+
+// resourceError is a failure to detect or assemble the resource.
+type resourceError struct {
+	err error
+}
+
+func (e *resourceError) Error() string {
+	return fmt.Sprintf("failed to create resource: %v", e.err)
+}
+
+func (e *resourceError) Unwrap() error { return e.err }
+
+// instrumentError is a failure to create the named instrument.
+type instrumentError struct {
+	instrument string
+	err        error
+}
+
+func (e *instrumentError) Error() string {
+	return fmt.Sprintf("failed to create %s: %v", e.instrument, e.err)
+}
+
+func (e *instrumentError) Unwrap() error { return e.err }
+
+// failedInstruments returns the names of every instrument err reports as
+// failed, looking through joined errors.
+func failedInstruments(err error) []string {
+	var instErr *instrumentError
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case interface{ Unwrap() []error }:
+		var names []string
+		for _, err := range e.Unwrap() {
+			names = append(names, failedInstruments(err)...)
+		}
+		return names
+	default:
+		if errors.As(err, &instErr) {
+			return []string{instErr.instrument}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// exportByteCounter accumulates the size of the OTLP requests sent to the
+// collector so it can be reported as otlp.export.bytes.
+type exportByteCounter struct {
+	exporter string
+	success  atomic.Int64
+	failure  atomic.Int64
+}
+
+func (c *exportByteCounter) add(n int64, ok bool) {
+	if ok {
+		c.success.Add(n)
+	} else {
+		c.failure.Add(n)
+	}
+}
+
+// grpcInterceptor measures the serialized size of each unary request.
+func (c *exportByteCounter) grpcInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if m, ok := req.(proto.Message); ok {
+			c.add(int64(proto.Size(m)), err == nil)
+		}
+		return err
+	}
+}
+
+// httpTransport wraps next, counting the request body bytes as the transport
+// reads them, since compressed bodies are sent with an unknown length. With
+// compression enabled this is the compressed size.
+func (c *exportByteCounter) httpTransport(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body == nil || req.Body == http.NoBody {
+			return next.RoundTrip(req)
+		}
+		body := &countingBody{ReadCloser: req.Body, record: c.add}
+		req = req.Clone(req.Context())
+		req.Body = body
+		resp, err := next.RoundTrip(req)
+		body.answered(err == nil && resp.StatusCode < 300)
+		return resp, err
+	})
+}
+
+// countingBody counts the bytes read from a request body. The transport may
+// finish with the body after RoundTrip returns, so the count is recorded
+// once both the body is done (EOF or Close) and the outcome is known.
+type countingBody struct {
+	io.ReadCloser
+	record func(n int64, ok bool)
+
+	mu       sync.Mutex
+	n        int64
+	done     bool
+	hasReply bool
+	ok       bool
+	recorded bool
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	b.n += int64(n)
+	if err == io.EOF {
+		b.done = true
+	}
+	b.flushLocked()
+	b.mu.Unlock()
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.mu.Lock()
+	b.done = true
+	b.flushLocked()
+	b.mu.Unlock()
+	return err
+}
+
+func (b *countingBody) answered(ok bool) {
+	b.mu.Lock()
+	b.hasReply, b.ok = true, ok
+	b.flushLocked()
+	b.mu.Unlock()
+}
+
+func (b *countingBody) flushLocked() {
+	if b.done && b.hasReply && !b.recorded {
+		b.recorded = true
+		b.record(b.n, b.ok)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// registerExportBytesCounter creates the otlp.export.bytes observable counter.
+func registerExportBytesCounter(meter metric.Meter, c *exportByteCounter) error {
+	_, err := meter.Int64ObservableCounter("otlp.export.bytes",
+		metric.WithDescription("Size of the OTLP export requests sent to the collector"),
+		metric.WithUnit("By"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(c.success.Load(), metric.WithAttributes(
+				attribute.String("exporter", c.exporter),
+				attribute.String("outcome", "success"),
+			))
+			o.Observe(c.failure.Load(), metric.WithAttributes(
+				attribute.String("exporter", c.exporter),
+				attribute.String("outcome", "failure"),
+			))
+			return nil
+		}),
+	)
+	return err
+}
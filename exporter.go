@@ -0,0 +1,674 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+var errInjectedExport = errors.New("injected export failure")
+
+// exporterInfo describes a metric exporter that can be selected on the
+// command line.
+type exporterInfo struct {
+	name        string
+	description string
+	flags       []string
+	// selectedBy reports whether the shortcut flags pick this exporter. It
+	// is nil for exporters only -metrics-exporter can pick.
+	selectedBy func(cfg config) bool
+	// newExporters creates a push exporter's exporters, along with where
+	// each one sends to. It is nil for prometheus and none, which have no
+	// exporter to push through.
+	newExporters func(ctx context.Context, cfg config, exportBytes *exportByteCounter) ([]sdkmetric.Exporter, []string, error)
+}
+
+// supportedExporters lists every exporter config.exporterName can return, in
+// the order their shortcut flags take precedence. It is the only place that
+// maps flags to exporters.
+var supportedExporters = []exporterInfo{
+	{
+		name:        "prometheus",
+		description: "Serve metrics for scraping at http://localhost:2112/metrics",
+		flags:       []string{"-prometheus"},
+		selectedBy:  func(cfg config) bool { return cfg.usePrometheus },
+	},
+	{
+		name:         "kafka",
+		description:  "Produce OTLP protobuf payloads to a Kafka topic",
+		flags:        []string{"-kafka"},
+		selectedBy:   func(cfg config) bool { return cfg.kafka != "" },
+		newExporters: newKafkaExporters,
+	},
+	{
+		name:         "emf",
+		description:  "Write CloudWatch Embedded Metric Format JSON lines to stdout or a file",
+		flags:        []string{"-emf", "-emf-file"},
+		selectedBy:   func(cfg config) bool { return cfg.emf },
+		newExporters: newEMFExporters,
+	},
+	{
+		name:        "otlp-http",
+		description: "Push metrics to an OpenTelemetry Collector over OTLP/HTTP",
+		flags:       []string{"-otlp-protocol=http/protobuf", "-otlp-endpoint", "-otlp-http-path", "-reset-on-failure"},
+		selectedBy:  func(cfg config) bool { return cfg.otlpProtocol == "http/protobuf" },
+		newExporters: func(ctx context.Context, cfg config, exportBytes *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+			return newOTLPExporters(ctx, cfg, "otlp-http", exportBytes)
+		},
+	},
+	{
+		name:        "otlp-grpc",
+		description: "Push metrics to an OpenTelemetry Collector over OTLP/gRPC",
+		flags: []string{
+			"-otlp-protocol=grpc", "-otlp-endpoint", "-embedded-collector",
+			"-grpc-keepalive-time", "-grpc-keepalive-timeout", "-grpc-max-send-bytes", "-grpc-max-recv-bytes",
+			"-collector-health-check", "-fail-on-connect", "-reset-on-failure",
+		},
+		selectedBy: func(cfg config) bool { return cfg.otlpProtocol == "grpc" || len(cfg.otlpEndpoints) > 0 },
+		newExporters: func(ctx context.Context, cfg config, exportBytes *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+			return newOTLPExporters(ctx, cfg, "otlp-grpc", exportBytes)
+		},
+	},
+	{
+		// The default when no shortcut flag picks another exporter.
+		name:         "console",
+		description:  "Print metrics as JSON to stdout (default)",
+		newExporters: newConsoleExporters,
+	},
+	{
+		name:        "none",
+		description: "Record metrics without exporting them, for pure load generation",
+		flags:       []string{"-metrics-exporter=none"},
+	},
+}
+
+// pushExporterFlags configure every exporter with newExporters set.
+var pushExporterFlags = []string{
+	"-fail-export-rate", "-failure-threshold", "-fallback-stdout", "-max-batch-points", "-log-exports",
+	"-promote-resource-attrs", "-export-changed-only", "-export-cycles", "-interval-jitter", "-runtime-metrics-interval",
+}
+
+// lookupExporter returns the supportedExporters entry called name.
+func lookupExporter(name string) (exporterInfo, bool) {
+	for _, e := range supportedExporters {
+		if e.name == name {
+			return e, true
+		}
+	}
+	return exporterInfo{}, false
+}
+
+// isSupportedExporter reports whether name is listed in supportedExporters.
+func isSupportedExporter(name string) bool {
+	_, ok := lookupExporter(name)
+	return ok
+}
+
+func printExporters(w io.Writer) {
+	for _, e := range supportedExporters {
+		fmt.Fprintf(w, "%-12s %s\n", e.name, e.description)
+		if len(e.flags) > 0 {
+			fmt.Fprintf(w, "%-12s flags: %s\n", "", strings.Join(e.flags, " "))
+		}
+	}
+	var pull []string
+	for _, e := range supportedExporters {
+		if e.newExporters == nil {
+			pull = append(pull, e.name)
+		}
+	}
+	fmt.Fprintf(w, "Every exporter but %s also takes: %s\n", strings.Join(pull, " and "), strings.Join(pushExporterFlags, " "))
+}
+
+// otlpProtocols are the values of -otlp-protocol, named as in
+// OTEL_EXPORTER_OTLP_PROTOCOL. http/json is rejected since the Go exporter
+// has no JSON encoding.
+var otlpProtocols = []string{"grpc", "http/protobuf", "http/json"}
+
+// protocolAlias returns the handler of a deprecated boolean flag that
+// selects protocol when set.
+func protocolAlias(dst *string, name, protocol string) func(string) error {
+	return func(s string) error {
+		on, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		if on {
+			log.Printf("-%s is deprecated, use -otlp-protocol %s", name, protocol)
+			*dst = protocol
+		}
+		return nil
+	}
+}
+
+// pipeline is what newReaders builds for the selected exporter.
+type pipeline struct {
+	readers []sdkmetric.Reader
+	// healths and exportBytes back the collector.up and otlp.export.bytes
+	// self-metrics. They are unset when they don't apply to the exporter.
+	healths     []*healthExporter
+	exportBytes *exportByteCounter
+	// cycles is set with -export-cycles.
+	cycles *exportCycles
+}
+
+// newReaders builds the readers for the exporter selected by cfg. Push
+// exporters get one periodic reader each and are wrapped for batching,
+// failure injection and health tracking.
+func newReaders(ctx context.Context, cfg config) (*pipeline, error) {
+	if cfg.exporterName() == "prometheus" {
+		exporter, err := prometheus.New()
+		if err != nil {
+			return nil, &exporterError{exporter: "prometheus", err: err}
+		}
+		fmt.Println("Using Prometheus exporter")
+		fmt.Println("Metrics available at http://localhost:2112/metrics")
+		if cfg.failExportRate > 0 {
+			log.Printf("Ignoring -fail-export-rate: the Prometheus exporter is pull-based")
+		}
+		go func() {
+			// Use a dedicated mux so nothing registered on http.DefaultServeMux
+			// (such as net/http/pprof) is exposed on the metrics port.
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			if err := http.ListenAndServe(":2112", mux); err != nil {
+				log.Printf("Error starting HTTP server: %v", err)
+			}
+		}()
+		return &pipeline{readers: []sdkmetric.Reader{exporter}}, nil
+	}
+
+	if cfg.exporterName() == "none" {
+		// A manual reader that is never collected still lets the SDK
+		// aggregate every measurement, so all recording paths are exercised.
+		fmt.Println("No exporter configured; metrics will not be exported")
+		return &pipeline{readers: []sdkmetric.Reader{sdkmetric.NewManualReader()}}, nil
+	}
+
+	p := &pipeline{}
+	if cfg.exportCycles > 0 {
+		p.cycles = newExportCycles(cfg.exportCycles)
+	}
+	if name := cfg.exporterName(); name == "otlp-grpc" || name == "otlp-http" {
+		p.exportBytes = &exportByteCounter{exporter: name}
+	}
+
+	exporters, endpoints, err := newPushExporters(ctx, cfg, p.exportBytes)
+	if err != nil {
+		return nil, err
+	}
+	printPushExporters(cfg, endpoints)
+	// Every push exporter is built the same way, so the first one speaks
+	// for all of them.
+	printTemporality(os.Stdout, exporters[0])
+
+	if cfg.failExportRate > 0 {
+		fmt.Printf("Injecting export failures with probability %.2f\n", cfg.failExportRate)
+	}
+
+	// One reader per exporter, so a slow or unreachable endpoint does not
+	// hold up the others
+	for i, exporter := range exporters {
+		if cfg.maxBatchPoints > 0 {
+			exporter = &batchingExporter{Exporter: exporter, limit: cfg.maxBatchPoints}
+		}
+		if cfg.failExportRate > 0 {
+			exporter = &failingExporter{Exporter: exporter, rate: cfg.failExportRate}
+		}
+
+		reconnecting := &reconnectingExporter{
+			exporter:  exporter,
+			endpoint:  endpoints[i],
+			threshold: cfg.failureThreshold,
+		}
+		switch {
+		case cfg.exporterName() == "console":
+		case cfg.fallbackStdout:
+			// The console exporter doesn't fail, so once switched the
+			// threshold is not reached again and the fallback is permanent.
+			reconnecting.fallback = true
+			endpoint := endpoints[i]
+			reconnecting.recreate = func() (sdkmetric.Exporter, error) {
+				log.Printf("Falling back to the console exporter for %s", endpoint)
+				return newConsoleExporter(cfg)
+			}
+		case cfg.resetOnFailure && (cfg.exporterName() == "otlp-grpc" || cfg.exporterName() == "otlp-http"):
+			endpoint := endpoints[i]
+			reconnecting.recreate = func() (sdkmetric.Exporter, error) {
+				exporter, err := newOTLPExporter(ctx, cfg, cfg.exporterName(), endpoint, p.exportBytes)
+				if err != nil {
+					return nil, err
+				}
+				if cfg.maxBatchPoints > 0 {
+					exporter = &batchingExporter{Exporter: exporter, limit: cfg.maxBatchPoints}
+				}
+				if cfg.failExportRate > 0 {
+					exporter = &failingExporter{Exporter: exporter, rate: cfg.failExportRate}
+				}
+				return exporter, nil
+			}
+		}
+
+		health := &healthExporter{Exporter: reconnecting, endpoint: endpoints[i], fallbackActive: reconnecting.fallbackActive}
+		p.healths = append(p.healths, health)
+		exporter = health
+		if cfg.logExports {
+			exporter = &loggingExporter{Exporter: exporter, endpoint: endpoints[i]}
+		}
+		if len(cfg.promoteAttrs) > 0 {
+			exporter = &promotingExporter{Exporter: exporter, keys: cfg.promoteAttrs}
+		}
+		if cfg.changedOnly {
+			exporter = &changedOnlyExporter{Exporter: exporter}
+		}
+		if p.cycles != nil {
+			exporter = p.cycles.wrap(exporter, reconnecting.fallbackActive)
+		}
+		p.readers = append(p.readers, sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(3*time.Second)))
+	}
+	return p, nil
+}
+
+// newPushExporters creates the exporters of the selected push exporter and
+// returns them along with where each one sends to. Callers report them with
+// printPushExporters. Failures are returned as an *exporterError.
+func newPushExporters(ctx context.Context, cfg config, exportBytes *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+	e, ok := lookupExporter(cfg.exporterName())
+	if !ok || e.newExporters == nil {
+		return nil, nil, &exporterError{exporter: cfg.exporterName(), err: errors.New("not a push exporter")}
+	}
+	exporters, endpoints, err := e.newExporters(ctx, cfg, exportBytes)
+	if err != nil {
+		return nil, nil, &exporterError{exporter: e.name, err: err}
+	}
+	return exporters, endpoints, nil
+}
+
+func newConsoleExporters(_ context.Context, cfg config, _ *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+	exporter, err := newConsoleExporter(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []sdkmetric.Exporter{exporter}, []string{"stdout"}, nil
+}
+
+func newEMFExporters(_ context.Context, cfg config, _ *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+	exporter, err := newEMFExporter(cfg.emfFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	target := cfg.emfFile
+	if target == "" {
+		target = "stdout"
+	}
+	return []sdkmetric.Exporter{exporter}, []string{target}, nil
+}
+
+func newKafkaExporters(ctx context.Context, cfg config, _ *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+	exporter, err := newKafkaExporter(ctx, cfg.kafka)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []sdkmetric.Exporter{exporter}, []string{"kafka:" + cfg.kafka}, nil
+}
+
+// newOTLPExporters creates one OTLP exporter per endpoint.
+func newOTLPExporters(ctx context.Context, cfg config, name string, exportBytes *exportByteCounter) ([]sdkmetric.Exporter, []string, error) {
+	endpoints, err := otlpEndpoints(cfg, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	var exporters []sdkmetric.Exporter
+	for _, endpoint := range endpoints {
+		exporter, err := newOTLPExporter(ctx, cfg, name, endpoint, exportBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters, endpoints, nil
+}
+
+// printPushExporters tells the user where newPushExporters sends metrics.
+func printPushExporters(cfg config, endpoints []string) {
+	switch cfg.exporterName() {
+	case "console":
+		fmt.Println("Using console exporter")
+		return
+	case "kafka":
+		fmt.Printf("Using Kafka exporter (%s)\n", cfg.kafka)
+		return
+	case "emf":
+		fmt.Printf("Using CloudWatch EMF exporter (%s)\n", endpoints[0])
+		return
+	case "otlp-http":
+		fmt.Println("Using OTLP HTTP exporter")
+	default:
+		fmt.Println("Using OTLP gRPC exporter")
+	}
+	for _, endpoint := range endpoints {
+		fmt.Printf("Exporting to %s\n", endpoint)
+	}
+}
+
+// otlpEndpoints returns the endpoints to export to. Following the OTLP
+// exporter spec, OTEL_EXPORTER_OTLP_METRICS_ENDPOINT is used as is and takes
+// precedence over OTEL_EXPORTER_OTLP_ENDPOINT, which for HTTP gets
+// /v1/metrics appended. Without either, -otlp-endpoint or the local default
+// for the protocol is used. With -embedded-collector its address always wins,
+// since nothing else would receive what it is there to count.
+func otlpEndpoints(cfg config, exporterName string) ([]string, error) {
+	if cfg.embedded {
+		return cfg.otlpEndpoints, nil
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"); v != "" {
+		return []string{v}, nil
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_ENDPOINT %q: %w", v, err)
+		}
+		if exporterName == "otlp-http" {
+			u.Path = path.Join("/", u.Path, "v1/metrics")
+		}
+		return []string{u.String()}, nil
+	}
+	if len(cfg.otlpEndpoints) > 0 {
+		return cfg.otlpEndpoints, nil
+	}
+	if exporterName == "otlp-http" {
+		return []string{"127.0.0.1:4318"}, nil
+	}
+	return []string{"127.0.0.1:4317"}, nil
+}
+
+func newConsoleExporter(cfg config) (sdkmetric.Exporter, error) {
+	var opts []stdoutmetric.Option
+	if cfg.useTUI {
+		// The dashboard owns the terminal, so keep the console exporter quiet.
+		opts = append(opts, stdoutmetric.WithWriter(io.Discard))
+	}
+	exporter, err := stdoutmetric.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create console exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// newOTLPExporter creates an OTLP exporter for a single endpoint, using HTTP
+// when name is otlp-http and gRPC otherwise. The endpoint is either host:port,
+// exported to without TLS, or a full URL as taken from the environment.
+// Request sizes are added to exportBytes.
+func newOTLPExporter(ctx context.Context, cfg config, name, endpoint string, exportBytes *exportByteCounter) (sdkmetric.Exporter, error) {
+	isURL := strings.Contains(endpoint, "://")
+
+	if name == "otlp-http" {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint), otlpmetrichttp.WithInsecure()}
+		if isURL {
+			opts = []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(endpoint)}
+		}
+		if cfg.otlpHTTPPath != "" {
+			opts = append(opts, otlpmetrichttp.WithURLPath(cfg.otlpHTTPPath))
+		}
+		opts = append(opts, otlpmetrichttp.WithHTTPClient(&http.Client{
+			Transport: exportBytes.httpTransport(http.DefaultTransport),
+		}))
+		exporter, err := otlpmetrichttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP HTTP exporter for %s: %w", endpoint, err)
+		}
+		return exporter, nil
+	}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint), otlpmetricgrpc.WithInsecure()}
+	if isURL {
+		opts = []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpointURL(endpoint)}
+	}
+	opts = append(opts, otlpmetricgrpc.WithDialOption(grpcDialOptions(cfg, exportBytes)...))
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP gRPC exporter for %s: %w", endpoint, err)
+	}
+	return exporter, nil
+}
+
+// grpcDialOptions returns the dial options of the gRPC exporter: the byte
+// counting interceptor plus the keepalive and message size settings.
+func grpcDialOptions(cfg config, exportBytes *exportByteCounter) []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithUnaryInterceptor(exportBytes.grpcInterceptor())}
+	if cfg.keepaliveTime > 0 {
+		// Pinging without an active RPC keeps load balancers from closing
+		// the connection in the 3 seconds between exports.
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.keepaliveTime,
+			Timeout:             cfg.keepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	var callOpts []grpc.CallOption
+	if cfg.grpcMaxSend > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(cfg.grpcMaxSend))
+	}
+	if cfg.grpcMaxRecv > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(cfg.grpcMaxRecv))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	return opts
+}
+
+// instrumentKinds lists every instrument kind an exporter chooses a
+// temporality for.
+var instrumentKinds = []sdkmetric.InstrumentKind{
+	sdkmetric.InstrumentKindCounter,
+	sdkmetric.InstrumentKindUpDownCounter,
+	sdkmetric.InstrumentKindHistogram,
+	sdkmetric.InstrumentKindGauge,
+	sdkmetric.InstrumentKindObservableCounter,
+	sdkmetric.InstrumentKindObservableUpDownCounter,
+	sdkmetric.InstrumentKindObservableGauge,
+}
+
+// printTemporality lists the temporality the exporter's selector assigns to
+// each instrument kind. For the OTLP exporters it follows
+// OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE.
+func printTemporality(w io.Writer, exporter sdkmetric.Exporter) {
+	fmt.Fprintln(w, "Temporality by instrument kind:")
+	for _, kind := range instrumentKinds {
+		fmt.Fprintf(w, "  %-24s %s\n", kind, exporter.Temporality(kind))
+	}
+}
+
+// failingExporter wraps an exporter and fails a fraction of exports on
+// purpose so the export error path can be exercised without a broken collector.
+type failingExporter struct {
+	sdkmetric.Exporter
+	rate float64
+}
+
+func (e *failingExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if rand.Float64() < e.rate {
+		return errInjectedExport
+	}
+	return e.Exporter.Export(ctx, rm)
+}
+
+// reconnectingExporter counts consecutive export failures. When they reach
+// threshold it logs an error and, if recreate is set, swaps the wrapped
+// exporter for the one recreate returns: a fresh OTLP exporter so a stale
+// connection is dropped, or the console exporter as a fallback.
+type reconnectingExporter struct {
+	endpoint  string
+	threshold int
+	recreate  func() (sdkmetric.Exporter, error)
+	// fallback is set when recreate returns the console exporter rather
+	// than a fresh copy of the original one.
+	fallback bool
+
+	mu       sync.Mutex
+	exporter sdkmetric.Exporter
+	failures int
+	fellBack bool
+}
+
+func (e *reconnectingExporter) current() sdkmetric.Exporter {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.exporter
+}
+
+// fallbackActive reports whether exports now go to the console fallback
+// instead of the endpoint.
+func (e *reconnectingExporter) fallbackActive() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fellBack
+}
+
+func (e *reconnectingExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return e.current().Temporality(k)
+}
+
+func (e *reconnectingExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return e.current().Aggregation(k)
+}
+
+func (e *reconnectingExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	err := e.current().Export(ctx, rm)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err == nil {
+		if e.threshold > 0 && e.failures >= e.threshold {
+			log.Printf("Exports to %s recovered after %d consecutive failures", e.endpoint, e.failures)
+		}
+		e.failures = 0
+		return nil
+	}
+
+	e.failures++
+	if e.threshold <= 0 || e.failures != e.threshold {
+		return err
+	}
+	log.Printf("Exports to %s failed %d times in a row: %v", e.endpoint, e.failures, err)
+
+	if e.recreate != nil {
+		fresh, rerr := e.recreate()
+		if rerr != nil {
+			log.Printf("Error recreating exporter for %s: %v", e.endpoint, rerr)
+			return err
+		}
+		old := e.exporter
+		e.exporter = fresh
+		e.failures = 0
+		e.fellBack = e.fallback
+		log.Printf("Replaced exporter for %s", e.endpoint)
+
+		go func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = old.Shutdown(shutdownCtx)
+		}()
+	}
+	return err
+}
+
+func (e *reconnectingExporter) ForceFlush(ctx context.Context) error {
+	return e.current().ForceFlush(ctx)
+}
+
+func (e *reconnectingExporter) Shutdown(ctx context.Context) error {
+	return e.current().Shutdown(ctx)
+}
+
+// healthExporter remembers whether the most recent export succeeded so it can
+// be reported as the collector.up gauge. Once fallbackActive reports true the
+// endpoint counts as down, even though the console exports succeed.
+type healthExporter struct {
+	sdkmetric.Exporter
+	endpoint       string
+	fallbackActive func() bool
+	exported       atomic.Bool
+	up             atomic.Bool
+}
+
+func (e *healthExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	err := e.Exporter.Export(ctx, rm)
+	e.up.Store(err == nil && !e.fallbackActive())
+	e.exported.Store(true)
+	return err
+}
+
+// loggingExporter logs the number of data points and the duration of every
+// export.
+type loggingExporter struct {
+	sdkmetric.Exporter
+	endpoint string
+}
+
+func (e *loggingExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	points := 0
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points += dataPointCount(m.Data)
+		}
+	}
+	start := time.Now()
+	err := e.Exporter.Export(ctx, rm)
+	elapsed := time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		log.Printf("Exporting %d points to %s failed after %s: %v", points, e.endpoint, elapsed, err)
+	} else {
+		log.Printf("Exported %d points to %s in %s", points, e.endpoint, elapsed)
+	}
+	return err
+}
+
+// registerHealthGauge creates the collector.up observable gauge, reporting one
+// series per endpoint. An endpoint is not reported until its first export has
+// completed.
+func registerHealthGauge(meter metric.Meter, healths []*healthExporter) error {
+	_, err := meter.Int64ObservableGauge("collector.up",
+		metric.WithDescription("Whether the last export succeeded (1) or failed (0)"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			for _, e := range healths {
+				if !e.exported.Load() {
+					continue
+				}
+				var v int64
+				if e.up.Load() {
+					v = 1
+				}
+				o.Observe(v, metric.WithAttributes(attribute.String("endpoint", e.endpoint)))
+			}
+			return nil
+		}),
+	)
+	return err
+}
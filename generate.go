@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// instruments groups the metric instruments the demo records into.
+type instruments struct {
+	counter metric.Int64Counter
+	// cpu.usage is either gauge, which receives the changes and sums them
+	// to the usage, or cpuGauge, which receives the usage itself. The other
+	// one is nil.
+	gauge       metric.Float64UpDownCounter
+	cpuGauge    metric.Float64Gauge
+	connections metric.Int64UpDownCounter
+	histogram   metric.Float64Histogram
+	invalid     metric.Int64Counter
+	boundaries  []float64
+}
+
+// instrumentErrorModes lists the values accepted by -on-instrument-error:
+// exit, carry on without the failed instruments, or panic.
+var instrumentErrorModes = []string{"fail", "skip", "panic"}
+
+// durationBoundaries are the request.duration bucket boundaries in ms.
+var durationBoundaries = []float64{10, 50, 100, 200, 500, 1000, 2000}
+
+// newInstruments creates the demo's instruments. Each one that fails is
+// replaced by a no-op instrument and its error is joined into the returned
+// one as an *instrumentError, so a caller that chooses to carry on gets a
+// usable set. absoluteCPU creates cpu.usage as a gauge, for views that
+// aggregate it other than as a sum.
+func newInstruments(meter metric.Meter, absoluteCPU bool) (*instruments, error) {
+	var errs []error
+
+	counter, err := meter.Int64Counter("requests.total", metric.WithDescription("Total number of requests"))
+	if err != nil {
+		errs = append(errs, &instrumentError{instrument: "requests.total", err: err})
+		counter = noop.Int64Counter{}
+	}
+
+	var gauge metric.Float64UpDownCounter
+	var cpuGauge metric.Float64Gauge
+	if absoluteCPU {
+		cpuGauge, err = meter.Float64Gauge("cpu.usage", metric.WithDescription("Current CPU usage percentage"))
+		if err != nil {
+			errs = append(errs, &instrumentError{instrument: "cpu.usage", err: err})
+			cpuGauge = noop.Float64Gauge{}
+		}
+	} else {
+		gauge, err = meter.Float64UpDownCounter("cpu.usage", metric.WithDescription("Current CPU usage percentage"))
+		if err != nil {
+			errs = append(errs, &instrumentError{instrument: "cpu.usage", err: err})
+			gauge = noop.Float64UpDownCounter{}
+		}
+	}
+
+	connections, err := meter.Int64UpDownCounter("active.connections", metric.WithDescription("Number of open connections"))
+	if err != nil {
+		errs = append(errs, &instrumentError{instrument: "active.connections", err: err})
+		connections = noop.Int64UpDownCounter{}
+	}
+
+	histogram, err := meter.Float64Histogram("request.duration",
+		metric.WithDescription("Request duration in milliseconds"),
+		metric.WithExplicitBucketBoundaries(durationBoundaries...),
+	)
+	if err != nil {
+		errs = append(errs, &instrumentError{instrument: "request.duration", err: err})
+		histogram = noop.Float64Histogram{}
+	}
+
+	invalid, err := meter.Int64Counter("invalid_observations.total",
+		metric.WithDescription("Values rejected instead of recorded because they were NaN or infinite"),
+	)
+	if err != nil {
+		errs = append(errs, &instrumentError{instrument: "invalid_observations.total", err: err})
+		invalid = noop.Int64Counter{}
+	}
+
+	return &instruments{
+		counter:     counter,
+		gauge:       gauge,
+		cpuGauge:    cpuGauge,
+		connections: connections,
+		histogram:   histogram,
+		invalid:     invalid,
+		boundaries:  durationBoundaries,
+	}, errors.Join(errs...)
+}
+
+// recordDuration records v into request.duration. NaN and infinite values,
+// which can come from a latency CSV, would poison the sum and can't be
+// bucketed, so they are logged and counted in invalid_observations.total
+// instead. It reports whether v was recorded.
+func (inst *instruments) recordDuration(ctx context.Context, v float64, opts ...metric.RecordOption) bool {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		log.Printf("Skipping invalid request.duration value %v", v)
+		inst.invalid.Add(ctx, 1, metric.WithAttributes(attribute.String("instrument", "request.duration")))
+		return false
+	}
+	inst.histogram.Record(ctx, v, opts...)
+	return true
+}
+
+// recordCPU records usage into cpu.usage: as is into a gauge, or as the
+// change from last into the up-down counter.
+func (inst *instruments) recordCPU(ctx context.Context, usage, last float64, opts ...metric.MeasurementOption) {
+	if inst.cpuGauge != nil {
+		inst.cpuGauge.Record(ctx, usage, recordOptions(opts)...)
+		return
+	}
+	inst.gauge.Add(ctx, usage-last, addOptions(opts)...)
+}
+
+func recordOptions(opts []metric.MeasurementOption) []metric.RecordOption {
+	out := make([]metric.RecordOption, len(opts))
+	for i, o := range opts {
+		out[i] = o
+	}
+	return out
+}
+
+func addOptions(opts []metric.MeasurementOption) []metric.AddOption {
+	out := make([]metric.AddOption, len(opts))
+	for i, o := range opts {
+		out[i] = o
+	}
+	return out
+}
+
+// newInstrumentsWithRetry calls newInstruments up to attempts times, waiting
+// delay after each failure. If none succeeds it returns the last attempt's
+// instruments, with no-ops in place of the failed ones, and its error.
+func newInstrumentsWithRetry(meter metric.Meter, absoluteCPU bool, attempts int, delay time.Duration) (*instruments, error) {
+	var inst *instruments
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		inst, err = newInstruments(meter, absoluteCPU)
+		if err == nil {
+			return inst, nil
+		}
+		log.Printf("Creating instruments failed (attempt %d/%d): %v", attempt, attempts, err)
+		if attempt < attempts {
+			time.Sleep(delay)
+		}
+	}
+	return inst, err
+}
+
+// generateConfig controls a single run of generate.
+type generateConfig struct {
+	iterations int
+	interval   time.Duration
+	// burstSize, if set, records that many iterations back to back and
+	// waits interval only between bursts.
+	burstSize int
+	rand      *rand.Rand
+	scenario  scenario
+	// clock defaults to the real clock when nil.
+	clock clock
+	// errorRate, if set, replaces the scenario's statuses with a 500 drawn
+	// at this probability, or 200, and tags request.duration with it too.
+	errorRate float64
+	// sampleRate, if between 0 and 1, is the probability that an
+	// iteration records anything; the others only wait.
+	sampleRate float64
+	// statusAsInt records the status as an integer http.response.status_code.
+	statusAsInt bool
+	// counterAttrs, if set, replaces the method and status attributes of
+	// requests.total.
+	counterAttrs attrTemplate
+	// seriesLoad, if set, is recorded once in every iteration.
+	seriesLoad *seriesLoad
+	// recordsPerIter is the number of request.duration values recorded
+	// in each iteration; less than 1 means one.
+	recordsPerIter int
+	// pathSampleRate is the fraction of request.duration values that also
+	// carry url.path, the endpoint template expanded into a concrete path.
+	pathSampleRate float64
+	// latencySamples, if set, replaces the random request durations.
+	latencySamples []float64
+	// waveform and waveformPeriod shape the cpu.usage values, see cpuWaveform.
+	waveform       string
+	waveformPeriod time.Duration
+	// cpuBreakdown records cpu.usage as user, system and idle series.
+	cpuBreakdown bool
+	// envAttributes maps attribute keys to the environment variables their
+	// values are read from, again for every measurement.
+	envAttributes []attribute.KeyValue
+	// redact is applied to the attributes of every measurement.
+	redact redactor
+	// limit, if set, then caps their number.
+	limit *attributeLimiter
+	// onIteration, if set, is called after each iteration has been recorded.
+	onIteration func(iteration)
+}
+
+// iteration holds the values recorded in one pass of the loop.
+type iteration struct {
+	index     int
+	status    string
+	cpuUsage  float64
+	durations []float64
+	connDelta int64
+}
+
+// summary describes what a call to generate did.
+type summary struct {
+	iterations   int
+	statusCounts map[string]int
+	// skipped counts the iterations left out by sampleRate; they are not
+	// part of iterations.
+	skipped     int
+	interrupted bool
+	// latency covers every recorded request.duration value.
+	latency durationStats
+}
+
+// generate records cfg.iterations rounds of sample metrics, waiting
+// cfg.interval between them. It stops early when ctx is cancelled.
+func generate(ctx context.Context, inst *instruments, cfg generateConfig) summary {
+	sum := summary{statusCounts: make(map[string]int)}
+	r := cfg.rand
+	clk := cfg.clock
+	if clk == nil {
+		clk = realClock{}
+	}
+	start := clk.Now()
+	var lastCPUUsage float64
+	var lastCPUStates map[string]float64
+	var samples int
+
+	attrs := func(kvs ...attribute.KeyValue) metric.MeasurementOption {
+		for _, env := range cfg.envAttributes {
+			kvs = append(kvs, env.Key.String(os.Getenv(env.Value.AsString())))
+		}
+		return metric.WithAttributes(cfg.limit.apply(cfg.redact.apply(kvs))...)
+	}
+
+	for i := 0; i < cfg.iterations; i++ {
+		if i > 0 {
+			if cfg.burstSize <= 0 || i%cfg.burstSize == 0 {
+				select {
+				case <-ctx.Done():
+				case <-clk.After(cfg.interval):
+				}
+			}
+			if ctx.Err() != nil {
+				sum.interrupted = true
+				break
+			}
+		}
+
+		// The draw only happens when sampling, so a seeded run without
+		// -sample-rate keeps producing the same values.
+		if cfg.sampleRate > 0 && cfg.sampleRate < 1 && r.Float64() >= cfg.sampleRate {
+			sum.skipped++
+			continue
+		}
+
+		// Counter: Increment request count
+		status := cfg.scenario.Statuses.pick(r)
+		if cfg.errorRate > 0 {
+			// A single draw decides the outcome, which the histogram below
+			// reports with the same status.
+			status = "200"
+			if r.Float64() < cfg.errorRate {
+				status = "500"
+			}
+		}
+		counterAttrs := []attribute.KeyValue{
+			attribute.String("method", cfg.scenario.Methods.pick(r)),
+			cfg.statusAttr(status),
+		}
+		if cfg.counterAttrs != nil {
+			counterAttrs = cfg.counterAttrs.resolve(map[string]string{
+				"method": counterAttrs[0].Value.AsString(),
+				"status": status,
+			})
+		}
+		inst.counter.Add(ctx, 1, attrs(counterAttrs...))
+
+		// Gauge: Set current CPU usage (using UpDownCounter as gauge alternative).
+		// Only the change is added, so the sum equals the latest value,
+		// unless cpu.usage is a real gauge for -gauge-aggregation.
+		cpuUsage := cpuWaveform(cfg.waveform, r, clk.Now().Sub(start), cfg.waveformPeriod)
+		if cfg.cpuBreakdown {
+			// Split the busy share between user and system; with idle the
+			// three states always add up to 100%.
+			user := cpuUsage * (0.5 + 0.3*r.Float64())
+			states := map[string]float64{
+				"user":   user,
+				"system": cpuUsage - user,
+				"idle":   100 - cpuUsage,
+			}
+			for _, state := range cpuStates {
+				inst.recordCPU(ctx, states[state], lastCPUStates[state], attrs(
+					attribute.String("host", "demo-host"),
+					attribute.String("state", state),
+				))
+			}
+			lastCPUStates = states
+		} else {
+			inst.recordCPU(ctx, cpuUsage, lastCPUUsage, attrs(
+				attribute.String("host", "demo-host"),
+			))
+			lastCPUUsage = cpuUsage
+		}
+
+		// Histogram: Record request durations
+		records := max(cfg.recordsPerIter, 1)
+		durations := make([]float64, 0, records)
+		for range records {
+			duration := r.Float64() * 1000 // 0-1000ms
+			if cfg.latencySamples != nil {
+				duration = cfg.latencySamples[samples%len(cfg.latencySamples)]
+			}
+			samples++
+			endpoint := cfg.scenario.Endpoints.pick(r)
+			kvs := []attribute.KeyValue{attribute.String("endpoint", endpoint)}
+			if cfg.pathSampleRate > 0 && r.Float64() < cfg.pathSampleRate {
+				kvs = append(kvs, attribute.String("url.path", expandRoute(endpoint, r)))
+			}
+			if cfg.errorRate > 0 {
+				kvs = append(kvs, cfg.statusAttr(status))
+			}
+			if inst.recordDuration(ctx, duration, attrs(kvs...)) {
+				durations = append(durations, duration)
+				sum.latency.add(duration)
+			}
+		}
+
+		// UpDownCounter: Open or close a few connections, so the sum can go down
+		connDelta := int64(r.Intn(7) - 3) // -3..+3
+		inst.connections.Add(ctx, connDelta, attrs(
+			attribute.String("type", cfg.scenario.ConnectionTypes.pick(r)),
+		))
+
+		if cfg.seriesLoad != nil {
+			cfg.seriesLoad.record(ctx)
+		}
+
+		sum.iterations++
+		sum.statusCounts[status]++
+
+		if cfg.onIteration != nil {
+			cfg.onIteration(iteration{
+				index:     i,
+				status:    status,
+				cpuUsage:  cpuUsage,
+				durations: durations,
+				connDelta: connDelta,
+			})
+		}
+	}
+
+	return sum
+}
+
+// attributeKeys returns the attribute keys generate records with cfg.
+func (cfg generateConfig) attributeKeys() []attribute.Key {
+	status := cfg.statusAttr("0").Key
+	keys := []attribute.Key{"method", status, "host", "endpoint", "type"}
+	if cfg.counterAttrs != nil {
+		keys = append(cfg.counterAttrs.keys(), "host", "endpoint", "type")
+		if cfg.errorRate > 0 {
+			keys = append(keys, status)
+		}
+	}
+	if cfg.cpuBreakdown {
+		keys = append(keys, "state")
+	}
+	if cfg.pathSampleRate > 0 {
+		keys = append(keys, "url.path")
+	}
+	for _, env := range cfg.envAttributes {
+		keys = append(keys, env.Key)
+	}
+	return keys
+}
+
+// statusAttr returns the status attribute: the string "status" by default,
+// or with statusAsInt the semantic convention http.response.status_code,
+// which is an integer.
+func (cfg generateConfig) statusAttr(status string) attribute.KeyValue {
+	if cfg.statusAsInt {
+		// main checks that every status is numeric.
+		code, _ := strconv.Atoi(status)
+		return semconv.HTTPResponseStatusCode(code)
+	}
+	return attribute.String("status", status)
+}
+
+// cpuStates are the state attribute values recorded with -cpu-breakdown.
+var cpuStates = []string{"user", "system", "idle"}
+
+// cpuWaveforms lists the values accepted by -gauge-waveform.
+var cpuWaveforms = []string{"random", "sine", "sawtooth"}
+
+// cpuWaveform returns a CPU usage percentage between 0 and 100. "random" draws
+// it from r; "sine" and "sawtooth" derive it from the time elapsed since the
+// start of the run, repeating every period.
+func cpuWaveform(waveform string, r *rand.Rand, elapsed, period time.Duration) float64 {
+	phase := float64(elapsed%period) / float64(period)
+	switch waveform {
+	case "sine":
+		return 50 + 50*math.Sin(2*math.Pi*phase)
+	case "sawtooth":
+		return 100 * phase
+	default:
+		return r.Float64() * 100
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// collectorHealthTimeout bounds each pre-flight health check.
+const collectorHealthTimeout = 5 * time.Second
+
+// checkCollectorHealth calls the standard gRPC health service on endpoint,
+// which is host:port or a URL as accepted by newOTLPExporter. A collector that
+// doesn't implement the health service is assumed to be healthy.
+func checkCollectorHealth(ctx context.Context, endpoint string) error {
+	target := endpoint
+	creds := insecure.NewCredentials()
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+		}
+		target = u.Host
+		if u.Scheme == "https" {
+			creds = credentials.NewTLS(nil)
+		}
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, collectorHealthTimeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("health check of %s failed: %w", endpoint, err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("collector at %s is %s", endpoint, resp.GetStatus())
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// parseKafkaTarget splits the -kafka value "broker[,broker...],topic".
+func parseKafkaTarget(s string) (brokers []string, topic string, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 {
+		return nil, "", fmt.Errorf("%q is not in brokers,topic form", s)
+	}
+	brokers, topic = parts[:len(parts)-1], parts[len(parts)-1]
+	if topic == "" || slices.Contains(brokers, "") {
+		return nil, "", fmt.Errorf("%q has an empty broker or topic", s)
+	}
+	return brokers, topic, nil
+}
+
+// kafkaExporter produces every export to a Kafka topic as an OTLP protobuf
+// ExportMetricsServiceRequest, the otlp_proto encoding read by the
+// collector's Kafka receiver. The serialization is left to the OTLP/HTTP
+// exporter, whose requests are handed to the producer instead of a server.
+type kafkaExporter struct {
+	sdkmetric.Exporter
+	writer *kafka.Writer
+}
+
+func newKafkaExporter(ctx context.Context, target string) (*kafkaExporter, error) {
+	brokers, topic, err := parseKafkaTarget(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -kafka: %w", err)
+	}
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		RequiredAcks: kafka.RequireOne,
+		// Each export is a single synchronous message, so waiting for the
+		// default 1s batch to fill would only delay every export.
+		BatchTimeout: 10 * time.Millisecond,
+	}
+	exporter, err := otlpmetrichttp.New(ctx,
+		// The endpoint is never dialed; it only ends up in the request URL.
+		otlpmetrichttp.WithEndpoint("kafka"),
+		otlpmetrichttp.WithInsecure(),
+		otlpmetrichttp.WithHTTPClient(&http.Client{Transport: kafkaTransport{writer: writer}}),
+		// Producer errors are reported once rather than retried for a minute.
+		otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{Enabled: false}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka exporter: %w", err)
+	}
+	return &kafkaExporter{Exporter: exporter, writer: writer}, nil
+}
+
+// Shutdown shuts the OTLP exporter down and then closes the producer, which
+// flushes any messages still being sent.
+func (e *kafkaExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.Exporter.Shutdown(ctx), e.writer.Close())
+}
+
+// kafkaTransport answers OTLP/HTTP requests by producing their body.
+type kafkaTransport struct {
+	writer *kafka.Writer
+}
+
+func (t kafkaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.writer.WriteMessages(req.Context(), kafka.Message{Value: body}); err != nil {
+		return nil, fmt.Errorf("failed to produce to Kafka topic %s: %w", t.writer.Topic, err)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/x-protobuf"}},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadLatencySamples reads latency values in milliseconds from a CSV file.
+// Without a column name the first field of every line is used; with one, the
+// first line is treated as a header and the named column is read. Lines that
+// don't hold a number are skipped with a warning.
+func loadLatencySamples(path, column string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open latency CSV: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	index := 0
+	if column != "" {
+		header, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read latency CSV header: %w", err)
+		}
+		index = -1
+		for i, name := range header {
+			if strings.TrimSpace(name) == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("column %q not found in latency CSV header", column)
+		}
+	}
+
+	var samples []float64
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read latency CSV: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if index >= len(record) {
+			log.Printf("Skipping latency CSV line %d: missing column", line)
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[index]), 64)
+		if err != nil {
+			log.Printf("Skipping latency CSV line %d: %q is not a number", line, record[index])
+			continue
+		}
+		samples = append(samples, v)
+	}
+
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no numeric samples found in %s", path)
+	}
+	return samples, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+)
+
+// otelLogLevels maps -otel-log-level values to slog levels. The SDK logs
+// warnings at V(1), info at V(4) and debug at V(8), which the logr slog bridge
+// turns into slog levels -1, -4 and -8 respectively.
+var otelLogLevels = map[string]slog.Level{
+	"error": slog.LevelError,
+	"warn":  slog.Level(-1),
+	"info":  slog.Level(-4),
+	"debug": slog.Level(-8),
+}
+
+// setupOTelLogger routes the SDK's internal logr output to a slog logger on
+// stderr, filtered to the given level.
+func setupOTelLogger(level string) error {
+	lvl, ok := otelLogLevels[level]
+	if !ok {
+		return fmt.Errorf("unknown OpenTelemetry log level %q (want error, warn, info or debug)", level)
+	}
+
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
+	logger := slog.New(handler).With("component", "otel-sdk")
+	otel.SetLogger(logr.FromSlogHandler(logger.Handler()))
+	return nil
+}
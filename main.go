@@ -2,184 +2,1076 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"math/rand"
-	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/google/uuid"
+	promclient "github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/prometheus"
-	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
+// config holds the settings parsed from the command line.
+type config struct {
+	otlpProtocol     string
+	otlpHTTPPath     string
+	keepaliveTime    time.Duration
+	keepaliveTimeout time.Duration
+	grpcMaxSend      int
+	grpcMaxRecv      int
+	kafka            string
+	emf              bool
+	emfFile          string
+	queueName        string
+	usePrometheus    bool
+	useTUI           bool
+	failExportRate   float64
+	errorRate        float64
+	pathSampleRate   float64
+	sampleRate       float64
+	otelLogLevel     string
+	openMetrics      bool
+	once             bool
+	smoke            bool
+	minimalResource  bool
+	promoteAttrs     []attribute.Key
+	latencyCSV       string
+	latencyColumn    string
+	recordsPerIter   int
+	otlpEndpoints    stringList
+	startupDelay     time.Duration
+	listExporters    bool
+	printConfig      bool
+	diagAttrOrder    bool
+	cardinalityTest  int
+	growCardinality  bool
+	cardinalityLimit int
+	targetSeries     int
+	attrTemplate     string
+	summaryFormat    string
+	summaryFile      string
+	statusAsInt      bool
+	embedded         bool
+	maxAttrs         int
+	resourceFile     string
+	onInstrumentErr  string
+	dryRun           bool
+	drainTimeout     time.Duration
+	pprof            bool
+	intervalJitter   time.Duration
+	seed             int64
+	gaugeWaveform    string
+	waveformPeriod   time.Duration
+	gaugeAggregation string
+	cpuBreakdown     bool
+	noMinMax         bool
+	resourceStrict   bool
+	redactKeys       stringList
+	semconvLint      bool
+	envAttributes    stringList
+	fallbackStdout   bool
+	maxBatchPoints   int
+	healthCheck      bool
+	failOnConnect    bool
+	backfill         time.Duration
+	backfillStep     time.Duration
+	histogramPreagg  string
+	monotonicTS      bool
+	exportCycles     int
+	logExports       bool
+	changedOnly      bool
+	runtimeInterval  time.Duration
+	timeScale        float64
+	burst            bool
+	burstSize        int
+	burstGap         time.Duration
+	scenarioFile     string
+	instanceID       string
+	dropMetrics      stringList
+	scopeAttributes  stringList
+	failureThreshold int
+	resetOnFailure   bool
+	metricsExporter  string
+}
+
+// exporterName returns the name, as listed in supportedExporters, of the
+// exporter selected by the flags. -metrics-exporter wins over the shortcut
+// flags, and console is the default.
+func (c config) exporterName() string {
+	if c.metricsExporter != "" {
+		return c.metricsExporter
+	}
+	for _, e := range supportedExporters {
+		if e.selectedBy != nil && e.selectedBy(c) {
+			return e.name
+		}
+	}
+	return "console"
+}
+
+// pushExporter reports whether the selected exporter pushes through
+// exporters, so that the export wrappers and push-only modes apply.
+func (c config) pushExporter() bool {
+	e, ok := lookupExporter(c.exporterName())
+	return ok && e.newExporters != nil
+}
+
+// telemetry holds the providers set up by initOTel.
+type telemetry struct {
+	meterProvider *sdkmetric.MeterProvider
+	// dumpGatherer is set when -openmetrics-dump is enabled.
+	dumpGatherer promclient.Gatherer
+	scopeAttrs   []attribute.KeyValue
+	// cyclesDone is closed once -export-cycles exports have been delivered.
+	cyclesDone <-chan struct{}
+	// smokeReader is set with -smoke.
+	smokeReader *sdkmetric.ManualReader
+	// runtimeProvider is set with -runtime-metrics-interval. It has its own,
+	// slower readers, so it only carries the runtime instruments.
+	runtimeProvider *sdkmetric.MeterProvider
+	// queue is set with -queue; its simulation starts with the demo loop.
+	queue *workQueue
+	// readers holds every reader of both providers, so shutdown can drain
+	// them in parallel.
+	readers []sdkmetric.Reader
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// meter returns the demo's meter, carrying the -scope-attribute values on its
+// instrumentation scope.
+func (t *telemetry) meter() metric.Meter {
+	return t.meterProvider.Meter("otel-demo", metric.WithInstrumentationAttributes(t.scopeAttrs...))
+}
+
+// parseFlags parses the command line arguments into a config. Parse errors
+// are returned instead of printed, so main can explain them; -h and -help
+// print the usage and return flag.ErrHelp. The flag set stays bound to the
+// returned config, so it reflects fallbacks that run fills in later.
+func parseFlags(args []string) (*config, *flag.FlagSet, error) {
+	cfg := new(config)
+	fs := flag.NewFlagSet("otel-demo", flag.ContinueOnError)
+	fs.StringVar(&cfg.otlpProtocol, "otlp-protocol", "", "Export over OTLP with this protocol: "+strings.Join(otlpProtocols, ", "))
+	fs.BoolFunc("otlp-grpc", "Deprecated: use -otlp-protocol grpc", protocolAlias(&cfg.otlpProtocol, "otlp-grpc", "grpc"))
+	fs.BoolFunc("otlp-http", "Deprecated: use -otlp-protocol http/protobuf", protocolAlias(&cfg.otlpProtocol, "otlp-http", "http/protobuf"))
+	fs.BoolVar(&cfg.usePrometheus, "prometheus", false, "Use Prometheus exporter")
+	fs.BoolVar(&cfg.useTUI, "tui", false, "Show a live terminal dashboard instead of per-iteration logs")
+	fs.Float64Var(&cfg.failExportRate, "fail-export-rate", 0, "Debug: probability (0.0-1.0) that an export fails on purpose")
+	fs.StringVar(&cfg.otelLogLevel, "otel-log-level", "warn", "Verbosity of OpenTelemetry SDK logs: error, warn, info or debug")
+	fs.BoolVar(&cfg.openMetrics, "openmetrics-dump", false, "Print metrics in OpenMetrics text format to stdout after each iteration")
+	fs.BoolVar(&cfg.once, "once", false, "Run a single iteration and exit")
+	fs.BoolVar(&cfg.smoke, "smoke", false, "Record into every instrument kind once, flush, report OK/FAIL per instrument and exit")
+	fs.BoolVar(&cfg.minimalResource, "minimal-resource", false, "Only attach service.name to the resource")
+	fs.Func("promote-resource-attrs", "Comma-separated resource attribute keys to copy onto every data point (e.g. service.name,service.instance.id)", func(s string) error {
+		for _, key := range strings.Split(s, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.promoteAttrs = append(cfg.promoteAttrs, attribute.Key(key))
+			}
+		}
+		return nil
+	})
+	fs.BoolVar(&cfg.resourceStrict, "resource-strict", false, "Abort startup when any resource detector fails instead of continuing with a partial resource")
+	fs.Float64Var(&cfg.errorRate, "error-rate", 0, "Probability (0.0-1.0) that a request fails with status 500; also tags request.duration with the status")
+	fs.Float64Var(&cfg.pathSampleRate, "path-sample-rate", 0, "Fraction (0.0-1.0) of request.duration values that also carry the concrete url.path")
+	fs.Float64Var(&cfg.sampleRate, "sample-rate", 1, "Fraction (0.0-1.0] of iterations that record metrics; the rest only wait")
+	fs.IntVar(&cfg.recordsPerIter, "records-per-iteration", 1, "Number of request.duration values recorded per iteration")
+	fs.StringVar(&cfg.onInstrumentErr, "on-instrument-error", "fail", "What to do when an instrument can't be created: "+strings.Join(instrumentErrorModes, ", "))
+	fs.StringVar(&cfg.resourceFile, "resource-file", "", "JSON file with an object of extra resource attributes; built-in attributes take precedence")
+	fs.IntVar(&cfg.maxAttrs, "max-attributes-per-measurement", 0, "Drop attributes beyond this many per measurement, keeping the first by key (0 disables)")
+	fs.BoolVar(&cfg.embedded, "embedded-collector", false, "Export over OTLP/gRPC to a minimal in-process receiver that logs what it gets, no collector needed")
+	fs.BoolVar(&cfg.statusAsInt, "status-as-int", false, "Record the status as the integer attribute http.response.status_code instead of the string status")
+	fs.StringVar(&cfg.summaryFormat, "summary-format", "text", "Format of the end-of-run summary: "+strings.Join(summaryFormats, ", "))
+	fs.StringVar(&cfg.summaryFile, "summary-file", "", "Write the end-of-run summary to this file instead of stdout, where it would be mixed with the progress output")
+	fs.StringVar(&cfg.attrTemplate, "attr-template", "", "Attributes of requests.total as key=value pairs, with {method} and {status} filled in per iteration")
+	fs.StringVar(&cfg.latencyCSV, "latency-csv", "", "Replay request durations (ms) from a CSV file instead of random values")
+	fs.StringVar(&cfg.latencyColumn, "latency-column", "", "Named column to read from -latency-csv (first line is the header)")
+	fs.StringVar(&cfg.otlpHTTPPath, "otlp-http-path", "", "URL path of the OTLP/HTTP metrics receiver (default /v1/metrics)")
+	fs.DurationVar(&cfg.keepaliveTime, "grpc-keepalive-time", 0, "Ping the collector after this long without activity on the gRPC connection (at least 10s; 0 disables)")
+	fs.DurationVar(&cfg.keepaliveTimeout, "grpc-keepalive-timeout", 0, "Close the gRPC connection when a keepalive ping is not answered within this time (default 20s)")
+	fs.IntVar(&cfg.grpcMaxSend, "grpc-max-send-bytes", 0, "Largest gRPC export request in bytes (0 keeps the gRPC default)")
+	fs.IntVar(&cfg.grpcMaxRecv, "grpc-max-recv-bytes", 0, "Largest gRPC response in bytes (0 keeps the gRPC default of 4 MiB)")
+	fs.BoolVar(&cfg.emf, "emf", false, "Write metrics as CloudWatch Embedded Metric Format JSON lines")
+	fs.StringVar(&cfg.emfFile, "emf-file", "", "Append the -emf lines to this file instead of writing them to stdout")
+	fs.StringVar(&cfg.kafka, "kafka", "", "Produce OTLP protobuf payloads to Kafka, given as broker[,broker...],topic")
+	fs.Var(&cfg.otlpEndpoints, "otlp-endpoint", "OTLP endpoint (host:port) to export to; repeat to fan out to several collectors")
+	fs.DurationVar(&cfg.startupDelay, "startup-delay", 0, "Wait this long after setup before generating metrics (e.g. 5s)")
+	fs.BoolVar(&cfg.listExporters, "list-exporters", false, "List the supported exporters and exit")
+	fs.BoolVar(&cfg.printConfig, "print-config", false, "Print the effective configuration as JSON before starting")
+	fs.BoolVar(&cfg.diagAttrOrder, "diag-attr-order", false, "Show that equal attribute sets recorded in different orders form one series, then exit")
+	fs.IntVar(&cfg.targetSeries, "target-series", 0, "Also record exactly this many distinct series into load.series every iteration (0 disables)")
+	fs.BoolVar(&cfg.growCardinality, "cardinality-growth", false, "Keep adding new series to a test counter and log the heap in use, until interrupted")
+	fs.IntVar(&cfg.cardinalityLimit, "cardinality-limit", 0, "Series limit per instrument for -cardinality-growth (0 means no limit)")
+	fs.IntVar(&cfg.cardinalityTest, "cardinality-stress", 0, "Exceed this cardinality limit tenfold on a test counter, check the overflow series, then exit")
+	fs.BoolVar(&cfg.dryRun, "dry-run", false, "Validate the configuration and exit without generating metrics")
+	fs.DurationVar(&cfg.drainTimeout, "drain-timeout", 5*time.Second, "Maximum time to flush and shut down after the demo stops")
+	fs.BoolVar(&cfg.pprof, "pprof", false, "Serve net/http/pprof profiles at http://"+pprofAddr+"/debug/pprof/")
+	fs.DurationVar(&cfg.intervalJitter, "interval-jitter", 0, "Shift the export phase by a random offset of up to ±jitter")
+	fs.Int64Var(&cfg.seed, "seed", 0, "Seed for the random values (0 picks one from the clock)")
+	fs.StringVar(&cfg.gaugeWaveform, "gauge-waveform", "random", "Shape of cpu.usage values: "+strings.Join(cpuWaveforms, ", "))
+	fs.DurationVar(&cfg.waveformPeriod, "waveform-period", time.Minute, "Period of the sine and sawtooth waveforms")
+	fs.BoolVar(&cfg.noMinMax, "histogram-no-minmax", false, "Leave min and max out of histogram data points to reduce the payload")
+	fs.BoolVar(&cfg.cpuBreakdown, "cpu-breakdown", false, "Record cpu.usage as user, system and idle series that add up to 100%")
+	fs.StringVar(&cfg.gaugeAggregation, "gauge-aggregation", "sum", "Aggregation applied to cpu.usage by a view: "+strings.Join(cpuAggregations, ", "))
+	fs.BoolVar(&cfg.healthCheck, "collector-health-check", false, "Query the gRPC health service of each OTLP gRPC endpoint before starting")
+	fs.BoolVar(&cfg.failOnConnect, "fail-on-connect", false, "Abort instead of warning when -collector-health-check finds an unhealthy collector")
+	fs.DurationVar(&cfg.backfill, "backfill", 0, "Export historical data points covering this span before now, then exit (e.g. 1h)")
+	fs.DurationVar(&cfg.backfillStep, "backfill-step", time.Minute, "Time between the data points of -backfill")
+	fs.StringVar(&cfg.queueName, "queue", "", "Simulate a work queue with this name and report its depth as queue.depth")
+	fs.DurationVar(&cfg.runtimeInterval, "runtime-metrics-interval", 0, "Export Go runtime metrics through a separate reader at this interval (e.g. 60s; 0 disables)")
+	fs.BoolVar(&cfg.logExports, "log-exports", false, "Log the number of data points, endpoint and duration of every export")
+	fs.BoolVar(&cfg.changedOnly, "export-changed-only", false, "Skip cumulative sum and histogram points whose value hasn't changed since the last export (lossy)")
+	fs.IntVar(&cfg.exportCycles, "export-cycles", 0, "Exit once every exporter has delivered this many exports (0 disables)")
+	fs.BoolVar(&cfg.monotonicTS, "monotonic-timestamps", false, "Timestamp -backfill and -histogram-preagg points from the monotonic clock so they never go backwards")
+	fs.StringVar(&cfg.histogramPreagg, "histogram-preagg", "", "Export the pre-aggregated histogram in this JSON file, then exit")
+	fs.Float64Var(&cfg.timeScale, "time-scale", 1, "Speed up the pacing between iterations by this factor (2 = twice as fast, 0 = no waiting)")
+	fs.BoolVar(&cfg.burst, "burst", false, "Generate metrics in bursts of -burst-size iterations separated by -burst-gap")
+	fs.IntVar(&cfg.burstSize, "burst-size", 100, "Iterations recorded back to back in each burst")
+	fs.DurationVar(&cfg.burstGap, "burst-gap", 10*time.Second, "Quiet period between bursts")
+	fs.StringVar(&cfg.scenarioFile, "scenario", "", "JSON file with the (optionally weighted) attribute values to generate")
+	fs.StringVar(&cfg.instanceID, "instance-id", "", "service.instance.id resource attribute (default $SERVICE_INSTANCE_ID or a random UUID)")
+	fs.Var(&cfg.dropMetrics, "drop-metric", "Instrument name whose data is dropped by a view; repeatable")
+	fs.Var(&cfg.envAttributes, "attribute-from-env", "attr=ENVVAR attribute whose value is read from the environment on every recording; repeatable")
+	fs.BoolVar(&cfg.semconvLint, "semconv-lint", false, "Warn about recorded attribute keys that are not semantic convention keys")
+	fs.Var(&cfg.redactKeys, "redact", "Attribute key whose values are replaced with "+redactedValue+" before recording; repeatable")
+	fs.Var(&cfg.scopeAttributes, "scope-attribute", "key=value attribute for the instrumentation scope; repeatable")
+	fs.IntVar(&cfg.failureThreshold, "failure-threshold", 5, "Consecutive export failures before logging an error (0 disables)")
+	fs.BoolVar(&cfg.resetOnFailure, "reset-on-failure", false, "Recreate the OTLP exporter connection once -failure-threshold is reached")
+	fs.IntVar(&cfg.maxBatchPoints, "max-batch-points", 0, "Split each export into requests of at most this many data points (0 disables)")
+	fs.BoolVar(&cfg.fallbackStdout, "fallback-stdout", false, "Switch a push exporter (OTLP, Kafka or EMF) to the console exporter once -failure-threshold is reached; takes precedence over -reset-on-failure")
+	fs.StringVar(&cfg.metricsExporter, "metrics-exporter", "", "Exporter to use by name (see -list-exporters); overrides -otlp-protocol and -prometheus")
+
+	// Keep the flag package from printing the error followed by the whole
+	// usage text; only an explicit -h gets the usage.
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	fs.SetOutput(os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		fs.Usage()
+	}
+	if err == nil && fs.NArg() > 0 {
+		// Parsing stops at the first non-flag, so anything after it would
+		// otherwise be ignored silently.
+		err = fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return cfg, fs, err
+}
+
+// usageError is a command line that could not be parsed. main explains it
+// and exits with status 2 rather than 1.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
 func main() {
-	useGrpcExporter := flag.Bool("otlp-grpc", false, "Use OTLP gRPC exporter")
-	useHttpExporter := flag.Bool("otlp-http", false, "Use OTLP HTTP exporter")
-	usePrometheus := flag.Bool("prometheus", false, "Use Prometheus exporter")
-	flag.Parse()
+	err := run(os.Args[1:])
+	var usage *usageError
+	switch {
+	case err == nil:
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "otel-demo: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Run with -h to list every flag. Supported exporters:")
+		printExporters(os.Stderr)
+		os.Exit(2)
+	default:
+		log.Print(err)
+		var exporterErr *exporterError
+		var resourceErr *resourceError
+		switch {
+		case errors.As(err, &exporterErr):
+			log.Printf("Check the flags of the %s exporter; -list-exporters shows them", exporterErr.exporter)
+		case errors.As(err, &resourceErr):
+			log.Printf("Check -resource-file, or leave out -resource-strict to continue with a partial resource")
+		}
+		os.Exit(1)
+	}
+}
+
+// run runs the demo with the given command line arguments. Errors are
+// returned rather than fatal, so the deferred shutdowns still flush the
+// providers and restore the terminal.
+func run(args []string) error {
+	cfg, fs, err := parseFlags(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return &usageError{err: err}
+	}
+
+	if cfg.listExporters {
+		printExporters(os.Stdout)
+		return nil
+	}
+
+	if cfg.diagAttrOrder {
+		if err := diagAttributeOrder(context.Background(), os.Stdout); err != nil {
+			return fmt.Errorf("attribute order diagnostic failed: %w", err)
+		}
+		return nil
+	}
+
+	if cfg.cardinalityTest > 0 {
+		if err := cardinalityStress(context.Background(), os.Stdout, cfg.cardinalityTest); err != nil {
+			return fmt.Errorf("cardinality stress test failed: %w", err)
+		}
+		return nil
+	}
+
+	if cfg.cardinalityLimit < 0 {
+		return fmt.Errorf("invalid -cardinality-limit %d: must not be negative", cfg.cardinalityLimit)
+	}
+	if cfg.cardinalityLimit > 0 && !cfg.growCardinality {
+		return errors.New("-cardinality-limit only applies to -cardinality-growth")
+	}
+	if cfg.growCardinality {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := cardinalityGrowth(ctx, os.Stdout, cfg.cardinalityLimit); err != nil {
+			return fmt.Errorf("cardinality growth failed: %w", err)
+		}
+		return nil
+	}
+
+	if cfg.metricsExporter != "" && !isSupportedExporter(cfg.metricsExporter) {
+		return fmt.Errorf("unknown -metrics-exporter %q; run with -list-exporters to see the options", cfg.metricsExporter)
+	}
+
+	switch {
+	case cfg.otlpProtocol == "http/json":
+		return errors.New("unsupported -otlp-protocol http/json: the Go OTLP metric exporter only sends protobuf, use http/protobuf")
+	case cfg.otlpProtocol != "" && !slices.Contains(otlpProtocols, cfg.otlpProtocol):
+		return fmt.Errorf("invalid -otlp-protocol %q: must be one of %s", cfg.otlpProtocol, strings.Join(otlpProtocols, ", "))
+	}
+
+	if cfg.embedded && (cfg.metricsExporter != "" || cfg.usePrometheus || cfg.kafka != "" || cfg.emf || len(cfg.otlpEndpoints) > 0 || (cfg.otlpProtocol != "" && cfg.otlpProtocol != "grpc")) {
+		return errors.New("-embedded-collector chooses the exporter itself; drop the other exporter and endpoint flags")
+	}
+
+	if cfg.otlpHTTPPath != "" && !strings.HasPrefix(cfg.otlpHTTPPath, "/") {
+		return fmt.Errorf("invalid -otlp-http-path %q: must start with /", cfg.otlpHTTPPath)
+	}
+
+	switch {
+	case cfg.keepaliveTime < 0 || (cfg.keepaliveTime > 0 && cfg.keepaliveTime < 10*time.Second):
+		// gRPC silently raises anything lower to 10s.
+		return fmt.Errorf("invalid -grpc-keepalive-time %s: must be 0 or at least 10s", cfg.keepaliveTime)
+	case cfg.keepaliveTimeout < 0:
+		return fmt.Errorf("invalid -grpc-keepalive-timeout %s: must not be negative", cfg.keepaliveTimeout)
+	case cfg.keepaliveTimeout > 0 && cfg.keepaliveTime == 0:
+		return errors.New("-grpc-keepalive-timeout needs -grpc-keepalive-time")
+	case cfg.grpcMaxSend < 0:
+		return fmt.Errorf("invalid -grpc-max-send-bytes %d: must not be negative", cfg.grpcMaxSend)
+	case cfg.grpcMaxRecv < 0:
+		return fmt.Errorf("invalid -grpc-max-recv-bytes %d: must not be negative", cfg.grpcMaxRecv)
+	}
+
+	if err := setupOTelLogger(cfg.otelLogLevel); err != nil {
+		return fmt.Errorf("failed to set up OpenTelemetry logger: %w", err)
+	}
+
+	if cfg.openMetrics && cfg.useTUI {
+		// Both write to stdout, and the dump would scroll the dashboard away.
+		return errors.New("-openmetrics-dump can't be combined with -tui")
+	}
+
+	if cfg.failExportRate < 0 || cfg.failExportRate > 1 {
+		return fmt.Errorf("invalid -fail-export-rate %v: must be between 0.0 and 1.0", cfg.failExportRate)
+	}
+
+	if !slices.Contains(summaryFormats, cfg.summaryFormat) {
+		return fmt.Errorf("invalid -summary-format %q: must be one of %s", cfg.summaryFormat, strings.Join(summaryFormats, ", "))
+	}
+
+	if !slices.Contains(instrumentErrorModes, cfg.onInstrumentErr) {
+		return fmt.Errorf("invalid -on-instrument-error %q: must be one of %s", cfg.onInstrumentErr, strings.Join(instrumentErrorModes, ", "))
+	}
+
+	if cfg.maxAttrs < 0 {
+		return fmt.Errorf("invalid -max-attributes-per-measurement %d: must not be negative", cfg.maxAttrs)
+	}
+
+	if cfg.targetSeries < 0 {
+		return fmt.Errorf("invalid -target-series %d: must not be negative", cfg.targetSeries)
+	}
+
+	if cfg.failureThreshold < 0 {
+		return fmt.Errorf("invalid -failure-threshold %d: must not be negative", cfg.failureThreshold)
+	}
+	if cfg.failureThreshold == 0 && (cfg.resetOnFailure || cfg.fallbackStdout) {
+		return errors.New("-reset-on-failure and -fallback-stdout need a positive -failure-threshold")
+	}
+
+	if cfg.maxBatchPoints < 0 {
+		return fmt.Errorf("invalid -max-batch-points %d: must not be negative", cfg.maxBatchPoints)
+	}
+
+	if cfg.exportCycles < 0 {
+		return fmt.Errorf("invalid -export-cycles %d: must not be negative", cfg.exportCycles)
+	}
+
+	// The push-only flags act on the periodic readers' exports, which the
+	// prometheus and none exporters don't have, so they would be ignored.
+	if !cfg.pushExporter() {
+		var pushOnly string
+		fs.Visit(func(f *flag.Flag) {
+			if pushOnly == "" && slices.Contains(pushExporterFlags, "-"+f.Name) {
+				pushOnly = "-" + f.Name
+			}
+		})
+		if pushOnly != "" {
+			return fmt.Errorf("%s needs a push exporter, not %s", pushOnly, cfg.exporterName())
+		}
+	}
+
+	if cfg.backfill > 0 && cfg.backfillStep <= 0 {
+		return fmt.Errorf("invalid -backfill-step %s: must be positive", cfg.backfillStep)
+	}
+
+	if cfg.errorRate < 0 || cfg.errorRate > 1 {
+		return fmt.Errorf("invalid -error-rate %v: must be between 0.0 and 1.0", cfg.errorRate)
+	}
+	if cfg.sampleRate <= 0 || cfg.sampleRate > 1 {
+		return fmt.Errorf("invalid -sample-rate %v: must be greater than 0.0 and at most 1.0", cfg.sampleRate)
+	}
+	if cfg.pathSampleRate < 0 || cfg.pathSampleRate > 1 {
+		return fmt.Errorf("invalid -path-sample-rate %v: must be between 0.0 and 1.0", cfg.pathSampleRate)
+	}
+
+	if cfg.recordsPerIter < 1 {
+		return fmt.Errorf("invalid -records-per-iteration %d: must be at least 1", cfg.recordsPerIter)
+	}
+
+	if cfg.timeScale < 0 {
+		return fmt.Errorf("invalid -time-scale %v: must not be negative", cfg.timeScale)
+	}
+
+	if cfg.burst && (cfg.burstSize <= 0 || cfg.burstGap <= 0) {
+		return errors.New("invalid burst settings: -burst-size and -burst-gap must be positive")
+	}
+
+	if !slices.Contains(cpuWaveforms, cfg.gaugeWaveform) {
+		return fmt.Errorf("invalid -gauge-waveform %q: must be one of %s", cfg.gaugeWaveform, strings.Join(cpuWaveforms, ", "))
+	}
+	if !slices.Contains(cpuAggregations, cfg.gaugeAggregation) {
+		return fmt.Errorf("invalid -gauge-aggregation %q: must be one of %s", cfg.gaugeAggregation, strings.Join(cpuAggregations, ", "))
+	}
+	if cfg.waveformPeriod <= 0 {
+		return fmt.Errorf("invalid -waveform-period %s: must be positive", cfg.waveformPeriod)
+	}
+
+	envAttributes, err := parseKeyValues(cfg.envAttributes)
+	if err != nil {
+		return fmt.Errorf("invalid -attribute-from-env: %w", err)
+	}
+
+	var counterAttrs attrTemplate
+	if cfg.attrTemplate != "" {
+		counterAttrs, err = parseAttrTemplate(cfg.attrTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid -attr-template: %w", err)
+		}
+	}
+
+	sc := defaultScenario()
+	if cfg.scenarioFile != "" {
+		var err error
+		sc, err = loadScenario(cfg.scenarioFile)
+		if err != nil {
+			return fmt.Errorf("failed to load scenario: %w", err)
+		}
+		fmt.Printf("Using scenario from %s\n", cfg.scenarioFile)
+	}
+	if cfg.statusAsInt {
+		for _, v := range sc.Statuses {
+			if _, err := strconv.Atoi(v.Value); err != nil {
+				return fmt.Errorf("invalid -status-as-int: scenario status %q is not a number", v.Value)
+			}
+		}
+	}
+
+	var latencySamples []float64
+	if cfg.latencyCSV != "" {
+		var err error
+		latencySamples, err = loadLatencySamples(cfg.latencyCSV, cfg.latencyColumn)
+		if err != nil {
+			return fmt.Errorf("failed to load latency samples: %w", err)
+		}
+		fmt.Printf("Replaying %d latency samples from %s\n", len(latencySamples), cfg.latencyCSV)
+	}
+
+	if cfg.pprof {
+		startPprofServer()
+		fmt.Printf("Profiling available at http://%s/debug/pprof/\n", pprofAddr)
+	}
+
+	if cfg.instanceID == "" {
+		cfg.instanceID = os.Getenv("SERVICE_INSTANCE_ID")
+	}
+	if cfg.instanceID == "" {
+		cfg.instanceID = uuid.NewString()
+	}
+
+	if cfg.printConfig {
+		if err := printConfig(os.Stdout, fs, *cfg); err != nil {
+			return fmt.Errorf("failed to print configuration: %w", err)
+		}
+	}
+	if cfg.dryRun {
+		return nil
+	}
 
 	ctx := context.Background()
 
+	if cfg.embedded {
+		collector, err := startEmbeddedCollector()
+		if err != nil {
+			return fmt.Errorf("failed to start embedded collector: %w", err)
+		}
+		// Deferred before the telemetry shutdown, so it runs after the
+		// final flush has been received.
+		defer collector.stop()
+		cfg.otlpProtocol = "grpc"
+		cfg.otlpEndpoints = stringList{collector.addr()}
+		fmt.Printf("Embedded collector listening on %s\n", collector.addr())
+	}
+
+	if cfg.healthCheck {
+		if cfg.exporterName() != "otlp-grpc" {
+			log.Printf("Ignoring -collector-health-check: it only applies to the otlp-grpc exporter")
+		} else {
+			endpoints, err := otlpEndpoints(*cfg, "otlp-grpc")
+			if err != nil {
+				return fmt.Errorf("failed to resolve OTLP endpoints: %w", err)
+			}
+			for _, endpoint := range endpoints {
+				err := checkCollectorHealth(ctx, endpoint)
+				switch {
+				case err == nil:
+					fmt.Printf("Collector at %s is healthy\n", endpoint)
+				case cfg.failOnConnect:
+					return fmt.Errorf("collector health check failed: %w", err)
+				default:
+					log.Printf("Collector health check failed: %v", err)
+				}
+			}
+		}
+	}
+
+	// Hand-built data points are timestamped by the demo, not the SDK.
+	var directClock clock = realClock{}
+	if cfg.monotonicTS {
+		directClock = newMonotonicClock(realClock{}, startTime)
+	}
+
+	if cfg.backfill > 0 {
+		return exportDirectly(ctx, *cfg, "-backfill", func(exporter sdkmetric.Exporter, res *resource.Resource) {
+			n, err := runBackfill(ctx, exporter, res, cfg.backfill, cfg.backfillStep, directClock, rand.New(rand.NewSource(time.Now().UnixNano())))
+			if err != nil {
+				log.Printf("Backfill stopped after %d exports: %v", n, err)
+			} else {
+				fmt.Printf("Backfilled %d points covering the last %s\n", n, cfg.backfill)
+			}
+		})
+	}
+
+	if cfg.histogramPreagg != "" {
+		h, err := loadPreaggregatedHistogram(cfg.histogramPreagg)
+		if err != nil {
+			return fmt.Errorf("failed to load pre-aggregated histogram: %w", err)
+		}
+		return exportDirectly(ctx, *cfg, "-histogram-preagg", func(exporter sdkmetric.Exporter, res *resource.Resource) {
+			if err := exporter.Export(ctx, h.resourceMetrics(res, startTime, directClock.Now())); err != nil {
+				log.Printf("Error exporting pre-aggregated histogram: %v", err)
+			} else {
+				fmt.Printf("Exported %d pre-aggregated %s points\n", len(h.Points), h.Name)
+			}
+		})
+	}
+
 	// Initialize OpenTelemetry
-	shutdown, err := initOTel(ctx, *useGrpcExporter, *useHttpExporter, *usePrometheus)
+	tel, err := initOTel(ctx, *cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize OpenTelemetry: %v", err)
+		return fmt.Errorf("failed to initialize OpenTelemetry: %w", err)
 	}
-	defer shutdown()
+	defer tel.shutdown(cfg.drainTimeout)
+	printViews(os.Stdout, *cfg)
 
 	// Get meter
-	meter := otel.Meter("otel-demo")
+	meter := tel.meter()
 
 	// Create metrics instruments
-	counter, err := meter.Int64Counter("requests.total", metric.WithDescription("Total number of requests"))
+	// Aggregating cpu.usage other than as a sum needs the usage itself, which
+	// only a gauge records.
+	inst, err := newInstrumentsWithRetry(meter, cfg.gaugeAggregation != "sum", 3, 100*time.Millisecond)
 	if err != nil {
-		log.Fatalf("Failed to create counter: %v", err)
+		switch cfg.onInstrumentErr {
+		case "skip":
+			log.Printf("Skipping %s, which could not be created: %v", strings.Join(failedInstruments(err), ", "), err)
+		case "panic":
+			panic(err)
+		default:
+			return fmt.Errorf("failed to create instruments: %w", err)
+		}
 	}
 
-	gauge, err := meter.Float64UpDownCounter("cpu.usage", metric.WithDescription("Current CPU usage percentage"))
-	if err != nil {
-		log.Fatalf("Failed to create gauge: %v", err)
+	var load *seriesLoad
+	if cfg.targetSeries > 0 {
+		load, err = newSeriesLoad(meter, cfg.targetSeries)
+		if err != nil {
+			return fmt.Errorf("failed to set up -target-series: %w", err)
+		}
+		fmt.Printf("Recording %d series into load.series: %d methods x %d statuses x %d hosts\n",
+			load.distinct(), len(seriesMethods), len(seriesStatuses), load.hosts())
 	}
 
-	histogram, err := meter.Float64Histogram("request.duration",
-		metric.WithDescription("Request duration in milliseconds"),
-		metric.WithExplicitBucketBoundaries(10, 50, 100, 200, 500, 1000, 2000),
-	)
-	if err != nil {
-		log.Fatalf("Failed to create histogram: %v", err)
+	if cfg.smoke {
+		ok := runSmoke(ctx, os.Stdout, tel, tel.smokeReader, inst, cfg.drainTimeout)
+		if err := tel.shutdown(cfg.drainTimeout); err != nil {
+			return fmt.Errorf("failed to shut down after the smoke test: %w", err)
+		}
+		if !ok {
+			return errors.New("smoke test failed")
+		}
+		return nil
 	}
 
-	fmt.Println("OpenTelemetry Metrics Demo Started")
-	fmt.Println("Generating metrics... Press Ctrl+C to stop")
+	// Stop generating metrics as soon as SIGINT or SIGTERM arrives
+	loopCtx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
 
-	// Generate metrics continuously
-	for i := 0; i < 100; i++ {
-		// Counter: Increment request count
-		counter.Add(ctx, 1, metric.WithAttributes(
-			attribute.String("method", randomMethod()),
-			attribute.String("status", randomStatus()),
-		))
-
-		// Gauge: Set current CPU usage (using UpDownCounter as gauge alternative)
-		cpuUsage := rand.Float64() * 100
-		gauge.Add(ctx, cpuUsage, metric.WithAttributes(
-			attribute.String("host", "demo-host"),
-		))
-
-		// Histogram: Record request duration
-		duration := rand.Float64() * 1000 // 0-1000ms
-		histogram.Record(ctx, duration, metric.WithAttributes(
-			attribute.String("endpoint", randomEndpoint()),
-		))
-
-		fmt.Printf("Iteration %d: Counter +1, Gauge %.2f%%, Histogram %.2fms\n", i+1, cpuUsage, duration)
-		time.Sleep(2 * time.Second)
-	}
-
-	fmt.Println("Demo completed")
-}
-
-func initOTel(ctx context.Context, useGrpcExporter, useHttpExporter, usePrometheus bool) (func(), error) {
-	// Create resource
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName("otel-demo"),
-			semconv.ServiceVersion("1.0.0"),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+	if tel.cyclesDone != nil {
+		go func() {
+			select {
+			case <-loopCtx.Done():
+			case <-tel.cyclesDone:
+				fmt.Printf("Completed %d export cycles\n", cfg.exportCycles)
+				cancel()
+			}
+		}()
+	}
+
+	if tel.queue != nil {
+		tel.queue.simulate(loopCtx, realClock{}, time.Now().UnixNano())
 	}
 
-	// Create meter provider based on flag
-	var meterProvider *sdkmetric.MeterProvider
+	if cfg.startupDelay > 0 {
+		fmt.Printf("Delaying metric generation by %s\n", cfg.startupDelay)
+		select {
+		case <-loopCtx.Done():
+		case <-time.After(cfg.startupDelay):
+		}
+	}
 
-	if usePrometheus {
-		exporter, err := prometheus.New()
+	stats := newLiveStats(inst.boundaries)
+	var dashboard *tui
+	if cfg.useTUI {
+		dashboard, err = startTUI(cancel)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
+			return fmt.Errorf("failed to start TUI: %w", err)
 		}
-		meterProvider = sdkmetric.NewMeterProvider(
-			sdkmetric.WithResource(res),
-			sdkmetric.WithReader(exporter),
-		)
-		fmt.Println("Using Prometheus exporter")
-		fmt.Println("Metrics available at http://localhost:2112/metrics")
-		go func() {
-			http.Handle("/metrics", promhttp.Handler())
-			if err := http.ListenAndServe(":2112", nil); err != nil {
-				log.Printf("Error starting HTTP server: %v", err)
+		defer dashboard.stop()
+	} else {
+		fmt.Println("OpenTelemetry Metrics Demo Started")
+		fmt.Println("Generating metrics... Press Ctrl+C to stop")
+	}
+
+	seed := cfg.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	// Generate metrics continuously
+	iterations := 100
+	var burstSize int
+	if cfg.burst {
+		iterations = 10 * cfg.burstSize
+		burstSize = cfg.burstSize
+	}
+	if cfg.exportCycles > 0 {
+		// Run until the export cycles are done rather than for a fixed count.
+		iterations = math.MaxInt
+	}
+	if cfg.once {
+		iterations = 1
+	}
+	interval := 2 * time.Second
+	if cfg.burst {
+		interval = cfg.burstGap
+	}
+	// Without any waiting the waveforms would barely move, so -time-scale 0
+	// runs the loop on virtual time that advances by each interval instead.
+	var loopClock clock = scaledClock{clock: realClock{}, scale: cfg.timeScale}
+	if cfg.timeScale == 0 {
+		loopClock = newFakeClock(time.Now())
+	}
+	genCfg := generateConfig{
+		iterations:     iterations,
+		interval:       interval,
+		burstSize:      burstSize,
+		recordsPerIter: cfg.recordsPerIter,
+		errorRate:      cfg.errorRate,
+		pathSampleRate: cfg.pathSampleRate,
+		sampleRate:     cfg.sampleRate,
+		seriesLoad:     load,
+		counterAttrs:   counterAttrs,
+		statusAsInt:    cfg.statusAsInt,
+		clock:          loopClock,
+		rand:           rand.New(rand.NewSource(seed)),
+		scenario:       sc,
+		latencySamples: latencySamples,
+		waveform:       cfg.gaugeWaveform,
+		waveformPeriod: cfg.waveformPeriod,
+		cpuBreakdown:   cfg.cpuBreakdown,
+		envAttributes:  envAttributes,
+		redact:         newRedactor(cfg.redactKeys),
+		limit:          newAttributeLimiter(cfg.maxAttrs),
+		onIteration: func(it iteration) {
+			stats.requests++
+			stats.cpuUsage = it.cpuUsage
+			stats.connections += it.connDelta
+			var total float64
+			for _, d := range it.durations {
+				stats.observeDuration(d)
+				total += d
 			}
-		}()
-	} else if useGrpcExporter {
-		exporter, err := otlpmetricgrpc.New(ctx,
-			otlpmetricgrpc.WithEndpoint("127.0.0.1:4317"),
-			otlpmetricgrpc.WithInsecure(),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create OTLP gRPC exporter: %w", err)
+
+			if dashboard != nil {
+				dashboard.render(it.index+1, iterations, stats)
+			} else {
+				histogram := fmt.Sprintf("%.2fms", total)
+				if len(it.durations) > 1 {
+					histogram = fmt.Sprintf("%d values averaging %.2fms", len(it.durations), total/float64(len(it.durations)))
+				}
+				fmt.Printf("Iteration %d: Counter +1, Gauge %.2f%%, Histogram %s, Connections %+d\n", it.index+1, it.cpuUsage, histogram, it.connDelta)
+			}
+
+			if tel.dumpGatherer != nil {
+				if err := writeOpenMetrics(tel.dumpGatherer, os.Stdout); err != nil {
+					log.Printf("Error dumping OpenMetrics: %v", err)
+				}
+			}
+		},
+	}
+	if cfg.semconvLint {
+		lintAttributeKeys(genCfg.attributeKeys())
+	}
+
+	sum := generate(loopCtx, inst, genCfg)
+
+	switch {
+	case cfg.summaryFile != "":
+		if err := writeSummaryFile(cfg.summaryFile, cfg.summaryFormat, sum, cfg.sampleRate); err != nil {
+			return fmt.Errorf("failed to write -summary-file: %w", err)
 		}
-		meterProvider = sdkmetric.NewMeterProvider(
-			sdkmetric.WithResource(res),
-			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(3*time.Second))),
-		)
-		fmt.Println("Using OTLP gRPC exporter")
-	} else if useHttpExporter {
-		exporter, err := otlpmetrichttp.New(ctx,
-			otlpmetrichttp.WithEndpoint("127.0.0.1:4318"),
-			otlpmetrichttp.WithInsecure(),
+	case dashboard == nil:
+		if err := writeSummary(os.Stdout, cfg.summaryFormat, sum, cfg.sampleRate); err != nil {
+			log.Printf("Error writing summary: %v", err)
+		}
+	}
+	return nil
+}
+
+// exportDirectly hands each push exporter to fn, together with the resource,
+// for modes that build their data points by hand instead of through the SDK.
+// The exporters are shut down afterwards.
+func exportDirectly(ctx context.Context, cfg config, mode string, fn func(sdkmetric.Exporter, *resource.Resource)) error {
+	if !cfg.pushExporter() {
+		return fmt.Errorf("%s needs a push exporter, not %s", mode, cfg.exporterName())
+	}
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	exporters, endpoints, err := newPushExporters(ctx, cfg, &exportByteCounter{exporter: cfg.exporterName()})
+	if err != nil {
+		return err
+	}
+	printPushExporters(cfg, endpoints)
+	for _, exporter := range exporters {
+		fn(exporter, res)
+		if err := exporter.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down exporter: %v", err)
+		}
+	}
+	return nil
+}
+
+// formatCounts renders counts as "k=v" pairs sorted by key.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, counts[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// newResource builds the resource describing this process. Failures are
+// returned as a *resourceError.
+func newResource(ctx context.Context, cfg config) (*resource.Resource, error) {
+	var resOpts []resource.Option
+	if cfg.minimalResource {
+		resOpts = append(resOpts, resource.WithAttributes(semconv.ServiceName("otel-demo")))
+	} else {
+		resOpts = append(resOpts,
+			resource.WithAttributes(
+				semconv.ServiceName("otel-demo"),
+				semconv.ServiceVersion(serviceVersion),
+				semconv.ServiceInstanceID(cfg.instanceID),
+			),
+			resource.WithTelemetrySDK(),
+			resource.WithProcessRuntimeName(),
+			resource.WithProcessRuntimeVersion(),
+			resource.WithProcessRuntimeDescription(),
+			// These read the system, so they are the ones that can fail
+			// and leave a partial resource behind.
+			resource.WithHost(),
+			resource.WithOS(),
+			resource.WithContainer(),
 		)
+	}
+	// A detector that fails yields a partial resource holding whatever the
+	// others detected. That is only fatal with -resource-strict.
+	res, err := resource.New(ctx, resOpts...)
+	if errors.Is(err, resource.ErrPartialResource) && !cfg.resourceStrict {
+		log.Printf("Continuing with partially detected resource: %v", err)
+	} else if err != nil {
+		return nil, &resourceError{err: err}
+	}
+	if cfg.resourceFile != "" {
+		merged, err := mergeResourceFile(res, cfg.resourceFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create OTLP HTTP exporter: %w", err)
+			return nil, &resourceError{err: err}
 		}
-		meterProvider = sdkmetric.NewMeterProvider(
-			sdkmetric.WithResource(res),
-			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(3*time.Second))),
-		)
-		fmt.Println("Using OTLP HTTP exporter")
-	} else {
-		exporter, err := stdoutmetric.New()
+		return merged, nil
+	}
+	return res, nil
+}
+
+func initOTel(ctx context.Context, cfg config) (*telemetry, error) {
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.intervalJitter > 0 {
+		// A periodic reader starts its ticker as soon as it is constructed,
+		// so delaying newReaders by a random amount in [0, 2*jitter] shifts
+		// the export phase by ±jitter relative to other instances. The
+		// export period itself is unchanged.
+		delay := time.Duration(rand.Int63n(int64(2*cfg.intervalJitter) + 1))
+		fmt.Printf("Delaying export schedule by %s for jitter\n", delay)
+		time.Sleep(delay)
+	}
+
+	// Create readers based on flag
+	pipe, err := newReaders(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithView(demoViews(cfg)...),
+	}
+
+	scopeAttrs, err := parseKeyValues(cfg.scopeAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -scope-attribute: %w", err)
+	}
+
+	tel := &telemetry{scopeAttrs: scopeAttrs, readers: pipe.readers}
+	if cfg.openMetrics {
+		// Use a dedicated registry so the dump only contains the demo's metrics
+		registry := promclient.NewRegistry()
+		dumpExporter, err := prometheus.New(prometheus.WithRegisterer(registry))
 		if err != nil {
-			return nil, fmt.Errorf("failed to create console exporter: %w", err)
+			return nil, fmt.Errorf("failed to create OpenMetrics dump exporter: %w", err)
 		}
-		meterProvider = sdkmetric.NewMeterProvider(
-			sdkmetric.WithResource(res),
-			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(3*time.Second))),
-		)
-		fmt.Println("Using console exporter")
+		tel.readers = append(tel.readers, dumpExporter)
+		tel.dumpGatherer = registry
+	}
+
+	if cfg.smoke {
+		tel.smokeReader = sdkmetric.NewManualReader()
+		tel.readers = append(tel.readers, tel.smokeReader)
+	}
+	for _, reader := range tel.readers {
+		opts = append(opts, sdkmetric.WithReader(reader))
 	}
 
+	tel.meterProvider = sdkmetric.NewMeterProvider(opts...)
+
 	// Set global meter provider
-	otel.SetMeterProvider(meterProvider)
+	otel.SetMeterProvider(tel.meterProvider)
+	if pipe.cycles != nil {
+		tel.cyclesDone = pipe.cycles.done
+	}
+
+	if err := registerUptimeGauge(tel.meter(), realClock{}, cfg.instanceID); err != nil {
+		return nil, fmt.Errorf("failed to register process.uptime gauge: %w", err)
+	}
 
-	return func() {
-		if err := meterProvider.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down meter provider: %v", err)
+	if err := registerNetworkBytesCounter(tel.meter()); err != nil {
+		return nil, fmt.Errorf("failed to register network.bytes counter: %w", err)
+	}
+
+	if cfg.runtimeInterval > 0 {
+		// Views and readers apply to a whole provider, so a different
+		// collection interval needs a second provider with its own exporters.
+		exporters, _, err := newPushExporters(ctx, cfg, pipe.exportBytes)
+		if err != nil {
+			return nil, err
+		}
+		runtimeOpts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+		for _, exporter := range exporters {
+			// The payload is shaped as on the main readers; failure handling,
+			// collector.up and -export-cycles only follow the main ones.
+			if cfg.maxBatchPoints > 0 {
+				exporter = &batchingExporter{Exporter: exporter, limit: cfg.maxBatchPoints}
+			}
+			if len(cfg.promoteAttrs) > 0 {
+				exporter = &promotingExporter{Exporter: exporter, keys: cfg.promoteAttrs}
+			}
+			if cfg.changedOnly {
+				exporter = &changedOnlyExporter{Exporter: exporter}
+			}
+			reader := sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(cfg.runtimeInterval))
+			tel.readers = append(tel.readers, reader)
+			runtimeOpts = append(runtimeOpts, sdkmetric.WithReader(reader))
+		}
+		tel.runtimeProvider = sdkmetric.NewMeterProvider(runtimeOpts...)
+		if err := registerRuntimeGauges(tel.runtimeProvider.Meter("otel-demo/runtime")); err != nil {
+			return nil, fmt.Errorf("failed to register runtime metrics: %w", err)
+		}
+		fmt.Printf("Collecting runtime metrics every %s\n", cfg.runtimeInterval)
+	}
+
+	if cfg.queueName != "" {
+		tel.queue = &workQueue{name: cfg.queueName}
+		if err := registerQueueDepth(tel.meter(), tel.queue); err != nil {
+			return nil, fmt.Errorf("failed to register queue.depth counter: %w", err)
+		}
+	}
+
+	if len(pipe.healths) > 0 {
+		if err := registerHealthGauge(tel.meter(), pipe.healths); err != nil {
+			return nil, fmt.Errorf("failed to register collector.up gauge: %w", err)
+		}
+	}
+
+	if pipe.exportBytes != nil {
+		if err := registerExportBytesCounter(tel.meter(), pipe.exportBytes); err != nil {
+			return nil, fmt.Errorf("failed to register otlp.export.bytes counter: %w", err)
+		}
+	}
+
+	if err := recordServiceStart(ctx, tel.meter(), cfg.instanceID); err != nil {
+		return nil, fmt.Errorf("failed to record service.starts.total: %w", err)
+	}
+
+	return tel, nil
+}
+
+// shutdown flushes and shuts down every reader concurrently, then the
+// providers, giving up once drainTimeout has elapsed. Only the first call does anything; later
+// calls return its result, so a deferred shutdown can't race one triggered
+// elsewhere.
+func (t *telemetry) shutdown(drainTimeout time.Duration) error {
+	t.shutdownOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+
+		// Each reader runs its own final export, so a slow collector only
+		// holds up its own reader rather than every one after it.
+		errs := make([]error, len(t.readers))
+		var wg sync.WaitGroup
+		for i, reader := range t.readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Only periodic readers hold data to push; pull readers
+				// have nothing to flush.
+				if f, ok := reader.(interface{ ForceFlush(context.Context) error }); ok {
+					if err := f.ForceFlush(ctx); err != nil {
+						log.Printf("Incomplete flush within drain timeout %s: %v", drainTimeout, err)
+					}
+				}
+				if err := reader.Shutdown(ctx); err != nil {
+					log.Printf("Error shutting down metric reader: %v", err)
+					errs[i] = err
+				}
+			}()
+		}
+		wg.Wait()
+
+		// The readers are already shut down, which the providers report as
+		// ErrReaderShutdown; anything else is a real failure.
+		providers := []*sdkmetric.MeterProvider{t.meterProvider}
+		if t.runtimeProvider != nil {
+			providers = append(providers, t.runtimeProvider)
+		}
+		for _, mp := range providers {
+			if err := mp.Shutdown(ctx); err != nil && !errors.Is(err, sdkmetric.ErrReaderShutdown) {
+				log.Printf("Error shutting down meter provider: %v", err)
+				errs = append(errs, err)
+			}
 		}
-	}, nil
+		t.shutdownErr = errors.Join(errs...)
+	})
+	return t.shutdownErr
 }
 
-func randomMethod() string {
-	methods := []string{"GET", "POST", "PUT", "DELETE"}
-	return methods[rand.Intn(len(methods))]
+// parseKeyValues turns "key=value" strings into string attributes.
+func parseKeyValues(pairs []string) ([]attribute.KeyValue, error) {
+	attrs := make([]attribute.KeyValue, 0, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not in key=value form", pair)
+		}
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	return attrs, nil
 }
 
-func randomStatus() string {
-	statuses := []string{"200", "404", "500"}
-	return statuses[rand.Intn(len(statuses))]
+// stringList is a flag.Value collecting every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-func randomEndpoint() string {
-	endpoints := []string{"/api/users", "/api/orders", "/api/products"}
-	return endpoints[rand.Intn(len(endpoints))]
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// registerNetworkBytesCounter creates the network.bytes observable counter.
+// On Linux it reports the totals from /proc/net/dev; elsewhere, or if that
+// file can't be read, it reports a simulated, steadily growing count.
+func registerNetworkBytesCounter(meter metric.Meter) error {
+	counter, err := meter.Float64ObservableCounter("network.bytes",
+		metric.WithDescription("Bytes received and transmitted over the network"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+
+	read := readProcNetDev
+	if runtime.GOOS != "linux" {
+		read = nil
+	} else if _, _, err := read(); err != nil {
+		fmt.Printf("Simulating network.bytes: %v\n", err)
+		read = nil
+	}
+	sim := &simulatedNetwork{rand: rand.New(rand.NewSource(rand.Int63()))}
+
+	receive := metric.WithAttributes(attribute.String("direction", "receive"))
+	transmit := metric.WithAttributes(attribute.String("direction", "transmit"))
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		var rx, tx float64
+		if read != nil {
+			var err error
+			if rx, tx, err = read(); err != nil {
+				return err
+			}
+		} else {
+			rx, tx = sim.next()
+		}
+		o.ObserveFloat64(counter, rx, receive)
+		o.ObserveFloat64(counter, tx, transmit)
+		return nil
+	}, counter)
+	return err
+}
+
+// readProcNetDev sums the received and transmitted bytes of every interface
+// listed in /proc/net/dev.
+func readProcNetDev() (rx, tx float64, err error) {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Data lines look like "eth0: rx_bytes rx_packets ... tx_bytes ...";
+		// the two header lines have no colon before the counters.
+		_, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		r, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse /proc/net/dev: %w", err)
+		}
+		t, err := strconv.ParseFloat(fields[8], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse /proc/net/dev: %w", err)
+		}
+		rx += r
+		tx += t
+	}
+	return rx, tx, scanner.Err()
+}
+
+// simulatedNetwork produces monotonically increasing byte counts.
+type simulatedNetwork struct {
+	mu     sync.Mutex
+	rand   *rand.Rand
+	rx, tx float64
+}
+
+func (s *simulatedNetwork) next() (rx, tx float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rx += s.rand.Float64() * 64 * 1024
+	s.tx += s.rand.Float64() * 16 * 1024
+	return s.rx, s.tx
+}
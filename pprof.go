@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+)
+
+const pprofAddr = "localhost:6060"
+
+// startPprofServer serves the net/http/pprof handlers under /debug/pprof/ on a
+// separate, loopback-only admin server.
+func startPprofServer() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		if err := http.ListenAndServe(pprofAddr, mux); err != nil {
+			log.Printf("Error starting pprof server: %v", err)
+		}
+	}()
+}
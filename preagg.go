@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// preaggregatedHistogram is the -histogram-preagg input: bucket counts that
+// were aggregated elsewhere, one point per attribute set.
+type preaggregatedHistogram struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Unit        string               `json:"unit"`
+	Boundaries  []float64            `json:"boundaries"`
+	Points      []preaggregatedPoint `json:"points"`
+}
+
+type preaggregatedPoint struct {
+	Attributes map[string]string `json:"attributes"`
+	// Counts holds one count per bucket, len(Boundaries)+1 in total.
+	Counts []uint64 `json:"counts"`
+	Sum    float64  `json:"sum"`
+}
+
+// loadPreaggregatedHistogram reads and validates a -histogram-preagg file.
+// The name defaults to request.duration.
+func loadPreaggregatedHistogram(path string) (*preaggregatedHistogram, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read histogram file: %w", err)
+	}
+	h := &preaggregatedHistogram{Name: "request.duration"}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse histogram file: %w", err)
+	}
+	if !slices.IsSorted(h.Boundaries) || len(slices.Compact(slices.Clone(h.Boundaries))) != len(h.Boundaries) {
+		return nil, fmt.Errorf("histogram boundaries must be strictly increasing")
+	}
+	if len(h.Points) == 0 {
+		return nil, fmt.Errorf("histogram file has no points")
+	}
+	for i, p := range h.Points {
+		if len(p.Counts) != len(h.Boundaries)+1 {
+			return nil, fmt.Errorf("point %d has %d counts, want %d (one per bucket)", i, len(p.Counts), len(h.Boundaries)+1)
+		}
+	}
+	return h, nil
+}
+
+// resourceMetrics builds the histogram data points directly, covering start
+// to now, instead of recording every value through the SDK.
+func (h *preaggregatedHistogram) resourceMetrics(res *resource.Resource, start, now time.Time) *metricdata.ResourceMetrics {
+	points := make([]metricdata.HistogramDataPoint[float64], 0, len(h.Points))
+	for _, p := range h.Points {
+		kvs := make([]attribute.KeyValue, 0, len(p.Attributes))
+		for k, v := range p.Attributes {
+			kvs = append(kvs, attribute.String(k, v))
+		}
+		var count uint64
+		for _, c := range p.Counts {
+			count += c
+		}
+		points = append(points, metricdata.HistogramDataPoint[float64]{
+			Attributes:   attribute.NewSet(kvs...),
+			StartTime:    start,
+			Time:         now,
+			Count:        count,
+			Bounds:       h.Boundaries,
+			BucketCounts: p.Counts,
+			Sum:          p.Sum,
+		})
+	}
+
+	return &metricdata.ResourceMetrics{
+		Resource: res,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope: instrumentation.Scope{Name: "otel-demo"},
+			Metrics: []metricdata.Metrics{{
+				Name:        h.Name,
+				Description: h.Description,
+				Unit:        h.Unit,
+				Data: metricdata.Histogram[float64]{
+					DataPoints:  points,
+					Temporality: metricdata.CumulativeTemporality,
+				},
+			}},
+		}},
+	}
+}
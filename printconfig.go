@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"strings"
+)
+
+// effectiveConfig is what -print-config writes: every flag after defaults,
+// environment variables and fallbacks have been applied.
+type effectiveConfig struct {
+	Exporter  string            `json:"exporter"`
+	Endpoints []string          `json:"endpoints,omitempty"`
+	Flags     map[string]string `json:"flags"`
+	// Env lists the OTEL_* variables the exporters read themselves. Headers
+	// carry credentials, so their values are redacted.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// printConfig writes the effective configuration as indented JSON. fs must
+// be the flag set parseFlags bound to cfg, with the fallbacks such as the
+// instance id already resolved in cfg.
+func printConfig(w io.Writer, fs *flag.FlagSet, cfg config) error {
+	ec := effectiveConfig{
+		Exporter: cfg.exporterName(),
+		Flags:    make(map[string]string),
+		Env:      make(map[string]string),
+	}
+	if ec.Exporter == "otlp-grpc" || ec.Exporter == "otlp-http" {
+		endpoints, err := otlpEndpoints(cfg, ec.Exporter)
+		if err != nil {
+			return err
+		}
+		ec.Endpoints = endpoints
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		// Deprecated aliases only write into other flags.
+		if strings.HasPrefix(f.Usage, "Deprecated:") {
+			return
+		}
+		ec.Flags[f.Name] = f.Value.String()
+	})
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "OTEL_") {
+			continue
+		}
+		if strings.Contains(name, "HEADERS") {
+			value = redactedValue
+		}
+		ec.Env[name] = value
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ec)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// promotingExporter copies the named resource attributes onto every data
+// point before exporting, for backends that ignore resource attributes.
+// Attributes already on a data point win over promoted ones.
+type promotingExporter struct {
+	sdkmetric.Exporter
+	keys []attribute.Key
+}
+
+func (e *promotingExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	var promoted []attribute.KeyValue
+	for _, key := range e.keys {
+		if v, ok := rm.Resource.Set().Value(key); ok {
+			promoted = append(promoted, attribute.KeyValue{Key: key, Value: v})
+		}
+	}
+	if len(promoted) == 0 {
+		return e.Exporter.Export(ctx, rm)
+	}
+
+	// The reader reuses rm and its data point slices between collects, so
+	// the promoted copy is built next to it rather than in place.
+	out := &metricdata.ResourceMetrics{Resource: rm.Resource}
+	for _, sm := range rm.ScopeMetrics {
+		scope := metricdata.ScopeMetrics{Scope: sm.Scope}
+		for _, m := range sm.Metrics {
+			m.Data = promoteAttributes(m.Data, promoted)
+			scope.Metrics = append(scope.Metrics, m)
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, scope)
+	}
+	return e.Exporter.Export(ctx, out)
+}
+
+// merged returns set with promoted added, keeping set's own values.
+func merged(set attribute.Set, promoted []attribute.KeyValue) attribute.Set {
+	return attribute.NewSet(append(append([]attribute.KeyValue(nil), promoted...), set.ToSlice()...)...)
+}
+
+// promoteAttributes returns a copy of data whose data points carry promoted.
+func promoteAttributes(data metricdata.Aggregation, promoted []attribute.KeyValue) metricdata.Aggregation {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64]:
+		d.DataPoints = promotePoints(d.DataPoints, promoted)
+		return d
+	case metricdata.Gauge[float64]:
+		d.DataPoints = promotePoints(d.DataPoints, promoted)
+		return d
+	case metricdata.Sum[int64]:
+		d.DataPoints = promotePoints(d.DataPoints, promoted)
+		return d
+	case metricdata.Sum[float64]:
+		d.DataPoints = promotePoints(d.DataPoints, promoted)
+		return d
+	case metricdata.Histogram[int64]:
+		d.DataPoints = promoteHistogramPoints(d.DataPoints, promoted)
+		return d
+	case metricdata.Histogram[float64]:
+		d.DataPoints = promoteHistogramPoints(d.DataPoints, promoted)
+		return d
+	default:
+		// The demo produces no exponential histograms or summaries.
+		return data
+	}
+}
+
+func promotePoints[N int64 | float64](pts []metricdata.DataPoint[N], promoted []attribute.KeyValue) []metricdata.DataPoint[N] {
+	out := make([]metricdata.DataPoint[N], len(pts))
+	for i, p := range pts {
+		p.Attributes = merged(p.Attributes, promoted)
+		out[i] = p
+	}
+	return out
+}
+
+func promoteHistogramPoints[N int64 | float64](pts []metricdata.HistogramDataPoint[N], promoted []attribute.KeyValue) []metricdata.HistogramDataPoint[N] {
+	out := make([]metricdata.HistogramDataPoint[N], len(pts))
+	for i, p := range pts {
+		p.Attributes = merged(p.Attributes, promoted)
+		out[i] = p
+	}
+	return out
+}
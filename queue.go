@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// workQueue is a simulated in-process queue: a producer enqueues work at a
+// random rate and a consumer drains it at a steadier one, so depth rises and
+// falls over time.
+type workQueue struct {
+	name  string
+	depth atomic.Int64
+}
+
+// registerQueueDepth creates the queue.depth observable up-down counter,
+// reading the queue's current depth on every collection.
+func registerQueueDepth(meter metric.Meter, q *workQueue) error {
+	depth, err := meter.Int64ObservableUpDownCounter("queue.depth",
+		metric.WithDescription("Number of items waiting in the work queue"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	attrs := metric.WithAttributes(attribute.String("queue.name", q.name))
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(depth, q.depth.Load(), attrs)
+		return nil
+	}, depth)
+	return err
+}
+
+// simulate runs the producer and consumer until ctx is done. Every 100ms the
+// producer enqueues 0-8 items and the consumer takes up to 4, so on average
+// they keep pace and the depth wanders up and back down to empty.
+func (q *workQueue) simulate(ctx context.Context, clk clock, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-clk.After(100 * time.Millisecond):
+				q.depth.Add(int64(r.Intn(9)))
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-clk.After(100 * time.Millisecond):
+				// Only the producer runs concurrently and it only adds, so
+				// the depth can't drop below what was loaded here.
+				q.depth.Add(-min(4, q.depth.Load()))
+			}
+		}
+	}()
+}
@@ -0,0 +1,29 @@
+package main
+
+import "go.opentelemetry.io/otel/attribute"
+
+// redactedValue replaces the value of every attribute named with -redact.
+const redactedValue = "[REDACTED]"
+
+// redactor holds the attribute keys whose values must never be recorded.
+// The zero value redacts nothing.
+type redactor map[attribute.Key]bool
+
+func newRedactor(keys []string) redactor {
+	r := make(redactor, len(keys))
+	for _, k := range keys {
+		r[attribute.Key(k)] = true
+	}
+	return r
+}
+
+// apply returns kvs with the values of redacted keys replaced. kvs is
+// modified in place.
+func (r redactor) apply(kvs []attribute.KeyValue) []attribute.KeyValue {
+	for i, kv := range kvs {
+		if r[kv.Key] {
+			kvs[i] = kv.Key.String(redactedValue)
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// loadResourceFile reads a flat JSON object of resource attributes. Strings,
+// booleans, integers and other numbers become string, bool, int64 and float64
+// attributes; anything else is rejected.
+func loadResourceFile(path string) ([]attribute.KeyValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to parse resource file %s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]attribute.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		if k == "" {
+			return nil, fmt.Errorf("empty key in resource file %s", path)
+		}
+		switch v := values[k].(type) {
+		case string:
+			attrs = append(attrs, attribute.String(k, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(k, v))
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				attrs = append(attrs, attribute.Int64(k, i))
+			} else if f, err := v.Float64(); err == nil {
+				attrs = append(attrs, attribute.Float64(k, f))
+			} else {
+				return nil, fmt.Errorf("value of %q in resource file %s is out of range: %w", k, path, err)
+			}
+		default:
+			return nil, fmt.Errorf("value of %q in resource file %s must be a string, bool or number, not %T", k, path, v)
+		}
+	}
+	return attrs, nil
+}
+
+// mergeResourceFile adds the attributes of the resource file to res. The
+// demo's own attributes win: a file shared between services must not
+// override e.g. service.name, so each conflicting key is logged and skipped.
+func mergeResourceFile(res *resource.Resource, path string) (*resource.Resource, error) {
+	attrs, err := loadResourceFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := res.Set()
+	for _, kv := range attrs {
+		if existing, ok := set.Value(kv.Key); ok && existing != kv.Value {
+			log.Printf("Ignoring %s=%s from %s: conflicts with the built-in value %s", kv.Key, kv.Value.Emit(), path, existing.Emit())
+		}
+	}
+	// Merge lets the second resource win on common keys.
+	merged, err := resource.Merge(resource.NewSchemaless(attrs...), res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge resource file %s: %w", path, err)
+	}
+	return merged, nil
+}
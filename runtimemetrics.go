@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"runtime"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// registerRuntimeGauges creates instruments reporting a snapshot of the Go
+// runtime. runtime.ReadMemStats stops the world, so these are meant for the
+// slow reader set up with -runtime-metrics-interval.
+func registerRuntimeGauges(meter metric.Meter) error {
+	heap, err := meter.Int64ObservableGauge("process.runtime.go.mem.heap_alloc",
+		metric.WithDescription("Bytes of allocated heap objects"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+	goroutines, err := meter.Int64ObservableGauge("process.runtime.go.goroutines",
+		metric.WithDescription("Number of goroutines that currently exist"),
+	)
+	if err != nil {
+		return err
+	}
+	gcCount, err := meter.Int64ObservableCounter("process.runtime.go.gc.count",
+		metric.WithDescription("Number of completed garbage collection cycles"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		o.ObserveInt64(heap, int64(ms.HeapAlloc))
+		o.ObserveInt64(goroutines, int64(runtime.NumGoroutine()))
+		o.ObserveInt64(gcCount, int64(ms.NumGC))
+		return nil
+	}, heap, goroutines, gcCount)
+	return err
+}
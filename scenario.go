@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// weightedValue is an attribute value with its relative selection weight.
+type weightedValue struct {
+	Value  string  `json:"value"`
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// weightedValues is a set of values to draw from. Without weights every value
+// is equally likely.
+type weightedValues []weightedValue
+
+// scenario describes the attribute values the generator draws from. It can be
+// loaded from a JSON file with -scenario; lists missing from the file keep
+// their defaults. Endpoints are route templates, where a {name} segment
+// stands for a path parameter, see expandRoute.
+type scenario struct {
+	Methods         weightedValues `json:"methods"`
+	Statuses        weightedValues `json:"statuses"`
+	Endpoints       weightedValues `json:"endpoints"`
+	ConnectionTypes weightedValues `json:"connection_types"`
+}
+
+func uniform(values ...string) weightedValues {
+	wv := make(weightedValues, len(values))
+	for i, v := range values {
+		wv[i] = weightedValue{Value: v}
+	}
+	return wv
+}
+
+func defaultScenario() scenario {
+	return scenario{
+		Methods:         uniform("GET", "POST", "PUT", "DELETE"),
+		Statuses:        uniform("200", "404", "500"),
+		Endpoints:       uniform("/api/users", "/api/users/{id}", "/api/orders", "/api/orders/{order_id}/items", "/api/products/{sku}"),
+		ConnectionTypes: uniform("http", "websocket", "grpc"),
+	}
+}
+
+func loadScenario(path string) (scenario, error) {
+	sc := defaultScenario()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sc, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return sc, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+
+	for name, values := range map[string]weightedValues{
+		"methods":          sc.Methods,
+		"statuses":         sc.Statuses,
+		"endpoints":        sc.Endpoints,
+		"connection_types": sc.ConnectionTypes,
+	} {
+		if err := values.validate(); err != nil {
+			return sc, fmt.Errorf("invalid %s in scenario %s: %w", name, path, err)
+		}
+	}
+	return sc, nil
+}
+
+// validate checks that there is at least one value and that weights are
+// either given for every value or for none.
+func (wv weightedValues) validate() error {
+	if len(wv) == 0 {
+		return fmt.Errorf("no values")
+	}
+	weighted := 0
+	for _, v := range wv {
+		if v.Weight < 0 {
+			return fmt.Errorf("negative weight for %q", v.Value)
+		}
+		if v.Weight > 0 {
+			weighted++
+		}
+	}
+	if weighted != 0 && weighted != len(wv) {
+		return fmt.Errorf("give a positive weight for every value or for none")
+	}
+	return nil
+}
+
+// pick draws a value, proportionally to its weight when weights are given.
+func (wv weightedValues) pick(r *rand.Rand) string {
+	var total float64
+	for _, v := range wv {
+		total += v.Weight
+	}
+	if total == 0 {
+		return wv[r.Intn(len(wv))].Value
+	}
+
+	n := r.Float64() * total
+	for _, v := range wv {
+		n -= v.Weight
+		if n < 0 {
+			return v.Value
+		}
+	}
+	return wv[len(wv)-1].Value
+}
+
+// routeParam matches a {name} path parameter in a route template.
+var routeParam = regexp.MustCompile(`\{[^/{}]+\}`)
+
+// expandRoute turns a route template into a concrete path by filling every
+// path parameter with a random ID, e.g. /api/users/{id} into /api/users/4821.
+func expandRoute(route string, r *rand.Rand) string {
+	return routeParam.ReplaceAllStringFunc(route, func(string) string {
+		return strconv.Itoa(1 + r.Intn(10000))
+	})
+}
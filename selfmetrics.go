@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// serviceVersion is reported as service.version.
+const serviceVersion = "1.0.0"
+
+// startTime is captured when the program starts and drives process.uptime.
+var startTime = time.Now()
+
+// registerUptimeGauge creates the process.uptime observable gauge reporting
+// the seconds elapsed since startTime according to clk, tagged with the
+// service.instance.id so restarts of the same instance line up.
+func registerUptimeGauge(meter metric.Meter, clk clock, instanceID string) error {
+	id := metric.WithAttributes(semconv.ServiceInstanceID(instanceID))
+	_, err := meter.Float64ObservableGauge("process.uptime",
+		metric.WithDescription("Time since the program started"),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+			o.Observe(clk.Now().Sub(startTime).Seconds(), id)
+			return nil
+		}),
+	)
+	return err
+}
+
+// recordServiceStart adds 1 to service.starts.total, once per process, so a
+// backend can count restarts by the increase of the sum.
+func recordServiceStart(ctx context.Context, meter metric.Meter, instanceID string) error {
+	starts, err := meter.Int64Counter("service.starts.total", metric.WithDescription("Number of times the service has started"))
+	if err != nil {
+		return err
+	}
+	starts.Add(ctx, 1, metric.WithAttributes(
+		semconv.ServiceVersion(serviceVersion),
+		semconv.ServiceInstanceID(instanceID),
+	))
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// semconvKeys are the semantic convention keys -semconv-lint accepts as is.
+var semconvKeys = map[attribute.Key]bool{
+	semconv.HTTPRequestMethodKey:      true,
+	semconv.HTTPResponseStatusCodeKey: true,
+	semconv.HTTPRouteKey:              true,
+	semconv.URLPathKey:                true,
+	semconv.NetworkProtocolNameKey:    true,
+	semconv.HostNameKey:               true,
+	semconv.ServiceNameKey:            true,
+}
+
+// semconvSuggestions maps common ad hoc keys, including the ones the demo
+// itself uses, to the convention key that should replace them.
+var semconvSuggestions = map[attribute.Key]attribute.Key{
+	"method":           semconv.HTTPRequestMethodKey,
+	"http.method":      semconv.HTTPRequestMethodKey,
+	"status":           semconv.HTTPResponseStatusCodeKey,
+	"status_code":      semconv.HTTPResponseStatusCodeKey,
+	"http.status_code": semconv.HTTPResponseStatusCodeKey,
+	"endpoint":         semconv.HTTPRouteKey,
+	"route":            semconv.HTTPRouteKey,
+	"type":             semconv.NetworkProtocolNameKey,
+	"protocol":         semconv.NetworkProtocolNameKey,
+	"host":             semconv.HostNameKey,
+	"hostname":         semconv.HostNameKey,
+}
+
+// lintAttributeKeys logs a warning for every key that isn't a known semantic
+// convention key, suggesting a replacement where there is an obvious one.
+func lintAttributeKeys(keys []attribute.Key) {
+	for _, key := range keys {
+		if semconvKeys[key] {
+			continue
+		}
+		if suggestion, ok := semconvSuggestions[key]; ok {
+			log.Printf("semconv-lint: attribute %q is not a semantic convention key, use %q", key, suggestion)
+		} else {
+			log.Printf("semconv-lint: attribute %q is not a known semantic convention key", key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// seriesMethods and seriesStatuses are the fixed leading dimensions of the
+// -target-series cross-product; host.id grows to cover the rest.
+var (
+	seriesMethods  = []string{"GET", "POST", "PUT", "DELETE"}
+	seriesStatuses = []string{"200", "404", "500"}
+)
+
+// seriesLoad records into exactly one data point per attribute set of a
+// deterministic cross-product, for a known cardinality load.
+type seriesLoad struct {
+	counter metric.Int64Counter
+	sets    []attribute.Set
+}
+
+// newSeriesLoad creates the load.series counter and enumerates n attribute
+// sets as a mixed-radix count over method, status and host.id: method varies
+// fastest, and the enumeration stops after n, so the last host may only get
+// some of the combinations but the total is exactly n.
+func newSeriesLoad(meter metric.Meter, n int) (*seriesLoad, error) {
+	counter, err := meter.Int64Counter("load.series",
+		metric.WithDescription("Cardinality load with one series per -target-series attribute set"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create load.series counter: %w", err)
+	}
+
+	perHost := len(seriesMethods) * len(seriesStatuses)
+	sets := make([]attribute.Set, n)
+	for i := range sets {
+		sets[i] = attribute.NewSet(
+			attribute.String("method", seriesMethods[i%len(seriesMethods)]),
+			attribute.String("status", seriesStatuses[i/len(seriesMethods)%len(seriesStatuses)]),
+			attribute.String("host.id", fmt.Sprintf("host-%d", i/perHost)),
+		)
+	}
+	return &seriesLoad{counter: counter, sets: sets}, nil
+}
+
+// distinct returns the number of different attribute sets, i.e. the number
+// of series the load produces.
+func (l *seriesLoad) distinct() int {
+	seen := make(map[attribute.Distinct]struct{}, len(l.sets))
+	for _, set := range l.sets {
+		seen[set.Equivalent()] = struct{}{}
+	}
+	return len(seen)
+}
+
+// hosts returns the number of host.id values the sets span.
+func (l *seriesLoad) hosts() int {
+	perHost := len(seriesMethods) * len(seriesStatuses)
+	return (len(l.sets) + perHost - 1) / perHost
+}
+
+// record adds 1 to every series.
+func (l *seriesLoad) record(ctx context.Context) {
+	for _, set := range l.sets {
+		l.counter.Add(ctx, 1, metric.WithAttributeSet(set))
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// smokeChecks are the instruments -smoke expects to find in the collected
+// data, covering every instrument kind the demo uses.
+var smokeChecks = []struct {
+	kind, name string
+}{
+	{"counter", "requests.total"},
+	{"up-down counter", "cpu.usage"},
+	{"up-down counter", "active.connections"},
+	{"histogram", "request.duration"},
+	{"observable gauge", "process.uptime"},
+	{"observable counter", "network.bytes"},
+}
+
+// runSmoke records one value into each synchronous instrument, checks that
+// every instrument in smokeChecks shows up when reader is collected, and then
+// flushes the real exporters. It writes an OK/FAIL line per check to w and
+// reports whether all of them passed.
+func runSmoke(ctx context.Context, w io.Writer, tel *telemetry, reader *sdkmetric.ManualReader, inst *instruments, flushTimeout time.Duration) bool {
+	attrs := metric.WithAttributes(attribute.String("smoke", "true"))
+	inst.counter.Add(ctx, 1, attrs)
+	inst.recordCPU(ctx, 1, 0, attrs)
+	inst.connections.Add(ctx, 1, attrs)
+	inst.histogram.Record(ctx, 1, attrs)
+
+	ok := true
+	report := func(label string, err error) {
+		if err != nil {
+			ok = false
+			fmt.Fprintf(w, "FAIL %s: %v\n", label, err)
+			return
+		}
+		fmt.Fprintf(w, "OK   %s\n", label)
+	}
+
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(ctx, &rm)
+	collected := make(map[string]bool)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if dataPointCount(m.Data) > 0 {
+				collected[m.Name] = true
+			}
+		}
+	}
+	for _, check := range smokeChecks {
+		kind := check.kind
+		if check.name == "cpu.usage" && inst.cpuGauge != nil {
+			kind = "gauge"
+		}
+		label := fmt.Sprintf("%s (%s)", check.name, kind)
+		switch {
+		case err != nil:
+			report(label, err)
+		case !collected[check.name]:
+			report(label, fmt.Errorf("no data collected"))
+		default:
+			report(label, nil)
+		}
+	}
+
+	flushCtx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+	report("export", tel.meterProvider.ForceFlush(flushCtx))
+	return ok
+}
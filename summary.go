@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// summaryFormats lists the values accepted by -summary-format.
+var summaryFormats = []string{"text", "json", "csv"}
+
+// durationStats tracks the recorded request.duration values.
+type durationStats struct {
+	count    int
+	sum      float64
+	min, max float64
+}
+
+func (s *durationStats) add(v float64) {
+	if s.count == 0 {
+		s.min, s.max = v, v
+	}
+	s.count++
+	s.sum += v
+	s.min = math.Min(s.min, v)
+	s.max = math.Max(s.max, v)
+}
+
+func (s durationStats) mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
+
+// summaryJSON is the -summary-format json schema. Fields are only ever
+// added, so scripts can rely on the existing ones.
+type summaryJSON struct {
+	Iterations   int            `json:"iterations"`
+	Skipped      int            `json:"skipped"`
+	Interrupted  bool           `json:"interrupted"`
+	StatusCounts map[string]int `json:"status_counts"`
+	LatencyMs    latencyJSON    `json:"latency_ms"`
+}
+
+type latencyJSON struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+}
+
+// writeSummaryFile writes the summary to path, replacing the file.
+func writeSummaryFile(path, format string, sum summary, sampleRate float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeSummary(f, format, sum, sampleRate); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeSummary writes what a run did in the given -summary-format. The
+// text form is the human one; json is a single object and csv has one
+// metric,value row per figure.
+func writeSummary(w io.Writer, format string, sum summary, sampleRate float64) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaryJSON{
+			Iterations:   sum.iterations,
+			Skipped:      sum.skipped,
+			Interrupted:  sum.interrupted,
+			StatusCounts: sum.statusCounts,
+			LatencyMs: latencyJSON{
+				Count: sum.latency.count,
+				Min:   sum.latency.min,
+				Max:   sum.latency.max,
+				Mean:  sum.latency.mean(),
+			},
+		})
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		rows := [][]string{
+			{"metric", "value"},
+			{"iterations", strconv.Itoa(sum.iterations)},
+			{"skipped", strconv.Itoa(sum.skipped)},
+			{"interrupted", strconv.FormatBool(sum.interrupted)},
+		}
+		statuses := make([]string, 0, len(sum.statusCounts))
+		for status := range sum.statusCounts {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			rows = append(rows, []string{"status." + status, strconv.Itoa(sum.statusCounts[status])})
+		}
+		rows = append(rows,
+			[]string{"latency_ms.count", strconv.Itoa(sum.latency.count)},
+			[]string{"latency_ms.min", strconv.FormatFloat(sum.latency.min, 'f', -1, 64)},
+			[]string{"latency_ms.max", strconv.FormatFloat(sum.latency.max, 'f', -1, 64)},
+			[]string{"latency_ms.mean", strconv.FormatFloat(sum.latency.mean(), 'f', -1, 64)},
+		)
+		return cw.WriteAll(rows)
+
+	default:
+		if sum.interrupted {
+			fmt.Fprintln(w, "Stopping metric generation")
+		}
+		fmt.Fprintf(w, "Demo completed: %d iterations, status counts %s\n", sum.iterations, formatCounts(sum.statusCounts))
+		if sum.skipped > 0 {
+			fmt.Fprintf(w, "Skipped %d iterations with -sample-rate %g\n", sum.skipped, sampleRate)
+		}
+		if sum.latency.count > 0 {
+			fmt.Fprintf(w, "Request duration: %d values, min %.2fms, mean %.2fms, max %.2fms\n",
+				sum.latency.count, sum.latency.min, sum.latency.mean(), sum.latency.max)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// templateVars are the generated values an -attr-template can refer to.
+var templateVars = []string{"method", "status"}
+
+// templateVar matches a {name} placeholder in a template value.
+var templateVar = regexp.MustCompile(`\{[^{}]*\}`)
+
+// attrTemplate is a list of attributes whose values may contain {name}
+// placeholders, filled in from the generated values on every recording.
+type attrTemplate []attribute.KeyValue
+
+// parseAttrTemplate parses a comma-separated key=value template such as
+// "method={method},status={status},tier=gold". Every placeholder must name
+// one of templateVars and every key may only appear once.
+func parseAttrTemplate(s string) (attrTemplate, error) {
+	kvs, err := parseKeyValues(strings.Split(s, ","))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[attribute.Key]bool, len(kvs))
+	for _, kv := range kvs {
+		if seen[kv.Key] {
+			return nil, fmt.Errorf("duplicate key %q", kv.Key)
+		}
+		seen[kv.Key] = true
+		for _, placeholder := range templateVar.FindAllString(kv.Value.AsString(), -1) {
+			if name := strings.Trim(placeholder, "{}"); !slices.Contains(templateVars, name) {
+				return nil, fmt.Errorf("unknown placeholder %s in %q, must be one of %s", placeholder, kv.Key, strings.Join(templateVars, ", "))
+			}
+		}
+	}
+	return attrTemplate(kvs), nil
+}
+
+// resolve returns the template's attributes with every placeholder replaced
+// by its entry in values.
+func (t attrTemplate) resolve(values map[string]string) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(t))
+	for i, kv := range t {
+		value := templateVar.ReplaceAllStringFunc(kv.Value.AsString(), func(placeholder string) string {
+			return values[strings.Trim(placeholder, "{}")]
+		})
+		kvs[i] = kv.Key.String(value)
+	}
+	return kvs
+}
+
+// keys returns the attribute keys the template produces.
+func (t attrTemplate) keys() []attribute.Key {
+	keys := make([]attribute.Key, len(t))
+	for i, kv := range t {
+		keys[i] = kv.Key
+	}
+	return keys
+}
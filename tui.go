@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// liveStats accumulates the values recorded by the demo loop so they can be
+// displayed without reading back from the SDK.
+type liveStats struct {
+	requests    int64
+	cpuUsage    float64
+	connections int64
+	boundaries  []float64
+	buckets     []int64
+}
+
+func newLiveStats(boundaries []float64) *liveStats {
+	return &liveStats{
+		boundaries: boundaries,
+		buckets:    make([]int64, len(boundaries)+1),
+	}
+}
+
+func (s *liveStats) observeDuration(v float64) {
+	for i, b := range s.boundaries {
+		if v <= b {
+			s.buckets[i]++
+			return
+		}
+	}
+	s.buckets[len(s.boundaries)]++
+}
+
+// tui is a minimal ANSI dashboard drawn on the alternate screen buffer.
+type tui struct {
+	fd       int
+	oldState *term.State
+}
+
+// startTUI switches the terminal to raw mode and cancels the given context
+// when q or Ctrl+C is pressed.
+func startTUI(cancel context.CancelFunc) (*tui, error) {
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set terminal raw mode: %w", err)
+	}
+
+	// Enter alternate screen and hide cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				cancel()
+				return
+			}
+			if n == 1 && (buf[0] == 'q' || buf[0] == 'Q' || buf[0] == 3) {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return &tui{fd: fd, oldState: oldState}, nil
+}
+
+func (t *tui) render(iteration, total int, s *liveStats) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "OpenTelemetry Metrics Demo  (iteration %d/%d, press q to quit)\r\n\r\n", iteration, total)
+	fmt.Fprintf(&b, "  requests.total     %d\r\n", s.requests)
+	fmt.Fprintf(&b, "  cpu.usage          %.2f%%\r\n", s.cpuUsage)
+	fmt.Fprintf(&b, "  active.connections %d\r\n\r\n", s.connections)
+	b.WriteString("  request.duration\r\n")
+
+	var maxCount int64
+	for _, c := range s.buckets {
+		maxCount = max(maxCount, c)
+	}
+	const barWidth = 40
+	for i, c := range s.buckets {
+		label := "+Inf"
+		if i < len(s.boundaries) {
+			label = fmt.Sprintf("%g", s.boundaries[i])
+		}
+		width := 0
+		if maxCount > 0 {
+			width = int(c * barWidth / maxCount)
+		}
+		fmt.Fprintf(&b, "  %6s ms | %-*s %d\r\n", label, barWidth, strings.Repeat("#", width), c)
+	}
+
+	fmt.Print(b.String())
+}
+
+// stop leaves the alternate screen and restores the terminal state.
+func (t *tui) stop() {
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	if err := term.Restore(t.fd, t.oldState); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring terminal: %v\n", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// cpuAggregations are the accepted values of -gauge-aggregation. sum is the
+// UpDownCounter's default aggregation and needs no view; the others record
+// cpu.usage as a gauge.
+var cpuAggregations = []string{"sum", "lastvalue", "histogram"}
+
+// viewSpec is a view along with a human-readable account of what it does,
+// kept next to each other so the startup summary can't drift from the views.
+type viewSpec struct {
+	instrument string
+	stream     sdkmetric.Stream
+	summary    string
+}
+
+// demoViews returns the views registered on the meter provider.
+func demoViews(cfg config) []sdkmetric.View {
+	specs := viewSpecs(cfg)
+	views := make([]sdkmetric.View, 0, len(specs))
+	for _, spec := range specs {
+		views = append(views, sdkmetric.NewView(sdkmetric.Instrument{Name: spec.instrument}, spec.stream))
+	}
+	return views
+}
+
+// printViews writes one line per view registered for cfg.
+func printViews(w io.Writer, cfg config) {
+	specs := viewSpecs(cfg)
+	fmt.Fprintf(w, "Registered %d views:\n", len(specs))
+	for _, spec := range specs {
+		fmt.Fprintf(w, "  %-20s %s\n", spec.instrument, spec.summary)
+	}
+}
+
+func viewSpecs(cfg config) []viewSpec {
+	var specs []viewSpec
+
+	// Dropped instruments are still recorded in code, but none of their data
+	// is aggregated or exported. Other views for the same instrument are left
+	// out so they don't bring the stream back.
+	dropped := make(map[string]bool)
+	for _, name := range cfg.dropMetrics {
+		dropped[name] = true
+		specs = append(specs, viewSpec{
+			instrument: name,
+			stream:     sdkmetric.Stream{Aggregation: sdkmetric.AggregationDrop{}},
+			summary:    "drop: no data is aggregated or exported",
+		})
+	}
+
+	if !dropped["requests.total"] {
+		specs = append(specs,
+			// Keep the dimensioned requests.total stream. Once any view matches
+			// an instrument the default stream is no longer produced, so it has
+			// to be listed explicitly next to the aggregated one below.
+			viewSpec{
+				instrument: "requests.total",
+				stream:     sdkmetric.Stream{Name: "requests.total"},
+				summary:    "keep as requests.total with all attributes",
+			},
+			// requests.total.all sums requests.total across every attribute,
+			// producing a single series.
+			viewSpec{
+				instrument: "requests.total",
+				stream: sdkmetric.Stream{
+					Name:            "requests.total.all",
+					AttributeFilter: func(attribute.KeyValue) bool { return false },
+				},
+				summary: "copy as requests.total.all with every attribute removed",
+			},
+		)
+	}
+
+	// With either of these, cpu.usage is a gauge recording the usage itself
+	// (see newInstruments); deltas would make them aggregate the changes.
+	if !dropped["cpu.usage"] {
+		switch cfg.gaugeAggregation {
+		case "lastvalue":
+			specs = append(specs, viewSpec{
+				instrument: "cpu.usage",
+				stream:     sdkmetric.Stream{Aggregation: sdkmetric.AggregationLastValue{}},
+				summary:    "aggregate as last value",
+			})
+		case "histogram":
+			boundaries := []float64{10, 25, 50, 75, 90}
+			specs = append(specs, viewSpec{
+				instrument: "cpu.usage",
+				stream: sdkmetric.Stream{Aggregation: sdkmetric.AggregationExplicitBucketHistogram{
+					Boundaries: boundaries,
+					NoMinMax:   cfg.noMinMax,
+				}},
+				summary: fmt.Sprintf("aggregate as histogram with buckets %v%s", boundaries, noMinMaxSummary(cfg)),
+			})
+		}
+	}
+
+	// An aggregation set by a view replaces the instrument's advisory
+	// boundaries, so they are passed again alongside NoMinMax.
+	if cfg.noMinMax && !dropped["request.duration"] {
+		specs = append(specs, viewSpec{
+			instrument: "request.duration",
+			stream: sdkmetric.Stream{Aggregation: sdkmetric.AggregationExplicitBucketHistogram{
+				Boundaries: durationBoundaries,
+				NoMinMax:   true,
+			}},
+			summary: "aggregate as histogram" + noMinMaxSummary(cfg),
+		})
+	}
+
+	return specs
+}
+
+func noMinMaxSummary(cfg config) string {
+	if cfg.noMinMax {
+		return " without min/max"
+	}
+	return ""
+}